@@ -1,35 +1,88 @@
 // internal/ast/ast.go - AST definitions
+//
+// Every node carries a Position (similar to go/token.Pos) so parser and
+// runtime errors can point at the exact file:line:column that produced
+// them instead of an untyped message.
 
 package ast
 
-type Node interface{}
+import "fmt"
+
+// Position locates a single point in a source file.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Position) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+// Node is implemented by every AST node.
+type Node interface {
+	Pos() Position
+}
+
+// posInfo is embedded by every node to satisfy Node without repeating
+// the Pos() boilerplate everywhere.
+type posInfo struct {
+	At Position
+}
+
+func (p posInfo) Pos() Position { return p.At }
 
 type Program struct {
+	posInfo
 	Statements []Node
+	// Comments holds every `@...`/`!...!` comment found while parsing,
+	// each attached to the node it immediately precedes. Only populated
+	// when parsed with parser.ParseComments; nil otherwise.
+	Comments CommentMap
+}
+
+// Comment is a single `@...` line comment or `!...!` block comment,
+// captured verbatim (delimiters stripped) by the tokenizer when parsing
+// with parser.ParseComments.
+type Comment struct {
+	Text string
+	Pos  Position
 }
 
+// CommentMap associates each comment with the node it was found
+// immediately before in the source, mirroring go/ast.CommentMap.
+type CommentMap map[Node][]*Comment
+
 type VarDecl struct {
+	posInfo
 	Name  string
 	Value Node
 }
 
 type Assign struct {
+	posInfo
 	Name  string
 	Value Node
 }
 
 type IfStmt struct {
+	posInfo
 	Condition Node
 	Then      []Node
 	Else      []Node
 }
 
 type WhileStmt struct {
+	posInfo
 	Condition Node
 	Body      []Node
 }
 
 type ForStmt struct {
+	posInfo
 	Var   string
 	Start Node
 	End   Node
@@ -37,43 +90,75 @@ type ForStmt struct {
 }
 
 type FuncDef struct {
+	posInfo
 	Name   string
 	Params []string
 	Body   []Node
 }
 
 type FuncCall struct {
-	Name string
-	Args []Node
+	posInfo
+	Name     string
+	Args     []Node
+	Resolved *Object // set by internal/resolver; nil until resolved
 }
 
 type BinOp struct {
+	posInfo
 	Op    string
 	Left  Node
 	Right Node
 }
 
 type Literal struct {
+	posInfo
 	Value float64
 }
 
 type StrLit struct {
+	posInfo
 	Value string
 }
 
 type Var struct {
-	Name string
+	posInfo
+	Name     string
+	Resolved *Object // set by internal/resolver; nil until resolved
 }
 
 type ImportStmt struct {
-	Name string
+	posInfo
+	Name  string
+	Alias string // optional, set by "as alias"; empty means bind as Name
+	// Angle records which surface syntax produced this node: false for
+	// "::name::" (the "import" token), true for "<name>" (the "from"
+	// token). Both resolve through the same ModuleGetter, so this only
+	// matters for round-tripping source through the printer.
+	Angle bool
 }
 
 type Embedded struct {
+	posInfo
 	Lang string
 	Code string
 }
 
 type WriteStmt struct {
+	posInfo
 	Expr Node
 }
+
+type ReturnStmt struct {
+	posInfo
+	Expr Node // nil for a bare "return"
+}
+
+// FuncLit is an anonymous function in expression position, e.g.
+// `fn(x){ return x*2 }`. A named `fn name(...){...}` statement still
+// parses as FuncDef.
+type FuncLit struct {
+	posInfo
+	Params []string
+	Body   []Node
+}
+
@@ -0,0 +1,76 @@
+// internal/ast/scope.go - Object and Scope, used by internal/resolver
+//
+// Modeled on the Object/Scope pair go/ast used for its (now legacy)
+// identifier resolution: a Scope is a flat map of names to the Object
+// that declared them, chained to its Outer scope so inner blocks can
+// see outer bindings.
+
+package ast
+
+// ObjKind says what kind of thing an Object names.
+type ObjKind int
+
+const (
+	ObjVar ObjKind = iota
+	ObjFunc
+	ObjParam
+	ObjImport
+	ObjBuiltin
+)
+
+func (k ObjKind) String() string {
+	switch k {
+	case ObjVar:
+		return "var"
+	case ObjFunc:
+		return "func"
+	case ObjParam:
+		return "param"
+	case ObjImport:
+		return "import"
+	case ObjBuiltin:
+		return "builtin"
+	default:
+		return "object"
+	}
+}
+
+// Object is a declared name: a var, a func, a param, or an import.
+type Object struct {
+	Kind ObjKind
+	Name string
+	Pos  Position
+	Used bool
+}
+
+// Scope holds the declarations visible at one lexical block. Outer is
+// nil for the file (top-level) scope.
+type Scope struct {
+	Outer   *Scope
+	Objects map[string]*Object
+}
+
+func NewScope(outer *Scope) *Scope {
+	return &Scope{Outer: outer, Objects: make(map[string]*Object)}
+}
+
+// Insert adds obj under obj.Name if that name isn't already declared in
+// this scope, returning the existing Object on a collision (the caller
+// decides whether that's an error).
+func (s *Scope) Insert(obj *Object) *Object {
+	if alt, ok := s.Objects[obj.Name]; ok {
+		return alt
+	}
+	s.Objects[obj.Name] = obj
+	return nil
+}
+
+// Lookup searches this scope and, if not found, every Outer scope.
+func (s *Scope) Lookup(name string) *Object {
+	for sc := s; sc != nil; sc = sc.Outer {
+		if obj, ok := sc.Objects[name]; ok {
+			return obj
+		}
+	}
+	return nil
+}
@@ -0,0 +1,503 @@
+// internal/compiler/compiler.go - Bytecode compiler
+//
+// Compiles an ast.Program into a linear sequence of opcodes that
+// internal/vm can execute directly, instead of walking the tree on
+// every run. Modeled loosely on Tengo's compiler: a symbolTable tracks
+// globals/locals, compilationScope holds the instructions being built
+// for the function currently in scope, and a SourceMap remembers which
+// source position produced each instruction so the VM can attach a
+// position to runtime errors.
+
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"nula-backend/internal/ast"
+)
+
+// Position is the ast.Position of whichever node produced an
+// instruction, re-exported under the compiler package so callers (the
+// VM) don't need to import internal/ast just for this type.
+type Position = ast.Position
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetBuiltin
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpPow
+	OpLessThan
+	OpTrue
+	OpFalse
+	OpJump
+	OpJumpFalse
+	OpCall
+	OpReturn
+	OpReturnValue
+	OpPop
+	OpPrint
+)
+
+// operandWidths gives the number of bytes each opcode's operands occupy.
+var operandWidths = map[Opcode][]int{
+	OpConstant:   {2},
+	OpGetGlobal:  {2},
+	OpSetGlobal:  {2},
+	OpGetLocal:   {1},
+	OpSetLocal:   {1},
+	OpGetBuiltin: {1},
+	OpJump:       {2},
+	OpJumpFalse:  {2},
+	OpCall:       {1},
+}
+
+// Make encodes an opcode and its operands into a single instruction.
+func Make(op Opcode, operands ...int) []byte {
+	widths, ok := operandWidths[op]
+	if !ok {
+		return []byte{byte(op)}
+	}
+	instrLen := 1
+	for _, w := range widths {
+		instrLen += w
+	}
+	instr := make([]byte, instrLen)
+	instr[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		switch widths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instr[offset:], uint16(o))
+		case 1:
+			instr[offset] = byte(o)
+		}
+		offset += widths[i]
+	}
+	return instr
+}
+
+// symbolScope distinguishes global from function-local bindings.
+type symbolScope int
+
+const (
+	globalScope symbolScope = iota
+	localScope
+)
+
+type symbol struct {
+	Name  string
+	Scope symbolScope
+	Index int
+}
+
+// symbolTable is a chain of scopes used to resolve names to storage
+// slots at compile time, so the VM can use array indices (OpGetLocal N)
+// instead of repeated map lookups.
+type symbolTable struct {
+	Outer *symbolTable
+
+	store          map[string]symbol
+	numDefinitions int
+}
+
+func newSymbolTable(outer *symbolTable) *symbolTable {
+	return &symbolTable{Outer: outer, store: make(map[string]symbol)}
+}
+
+func (s *symbolTable) Define(name string) symbol {
+	sym := symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		sym.Scope = globalScope
+	} else {
+		sym.Scope = localScope
+	}
+	s.store[name] = sym
+	s.numDefinitions++
+	return sym
+}
+
+func (s *symbolTable) Resolve(name string) (symbol, bool) {
+	sym, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		return s.Outer.Resolve(name)
+	}
+	return sym, ok
+}
+
+// own reports whether name is bound directly in s, as opposed to
+// having been found by Resolve walking out to an Outer table. Since
+// only FuncDef/FuncLit bodies open a new symbolTable (an if/while/for
+// shares its enclosing function's), "not own" for a localScope symbol
+// means it belongs to some *enclosing function's* locals, not just an
+// outer block of the same call.
+func (s *symbolTable) own(name string) bool {
+	_, ok := s.store[name]
+	return ok
+}
+
+// loop tracks the jump patch lists for the while/for currently being
+// compiled, so break/continue (once the language grows them) and the
+// end-of-loop jump can be backpatched once the loop body's length is
+// known.
+type loop struct {
+	Breaks    []int
+	Continues []int
+}
+
+type compilationScope struct {
+	Instructions []byte
+	SourceMap    map[int]Position
+
+	lastInstr Opcode
+	lastPos   int
+}
+
+// Bytecode is the final compiled program handed to vm.New.
+type Bytecode struct {
+	Instructions []byte
+	Constants    []interface{}
+	SourceMap    map[int]Position
+}
+
+// CompiledFunction is a constant representing a compiled fn body; the
+// VM pushes a frame for it on OpCall.
+type CompiledFunction struct {
+	Instructions  []byte
+	SourceMap     map[int]Position
+	NumParams     int
+	NumLocals     int
+}
+
+type Compiler struct {
+	constants []interface{}
+
+	symbolTable *symbolTable
+	scopes      []compilationScope
+	scopeIndex  int
+
+	loops []*loop
+
+	currentPos Position
+}
+
+func New() *Compiler {
+	main := compilationScope{SourceMap: make(map[int]Position)}
+	return &Compiler{
+		symbolTable: newSymbolTable(nil),
+		scopes:      []compilationScope{main},
+	}
+}
+
+func Compile(prog ast.Program) (*Bytecode, error) {
+	c := New()
+	if err := c.Compile(&prog); err != nil {
+		return nil, err
+	}
+	return c.Bytecode(), nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		SourceMap:    c.scopes[c.scopeIndex].SourceMap,
+	}
+}
+
+func (c *Compiler) currentInstructions() []byte {
+	return c.scopes[c.scopeIndex].Instructions
+}
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	ins := Make(op, operands...)
+	pos := len(c.currentInstructions())
+	scope := &c.scopes[c.scopeIndex]
+	scope.Instructions = append(scope.Instructions, ins...)
+	scope.SourceMap[pos] = c.currentPos
+	scope.lastInstr = op
+	scope.lastPos = pos
+	return pos
+}
+
+func (c *Compiler) addConstant(val interface{}) int {
+	c.constants = append(c.constants, val)
+	return len(c.constants) - 1
+}
+
+// patchJump rewrites the 2-byte operand of the jump instruction at pos
+// to target the current end of the instruction stream.
+func (c *Compiler) patchJump(pos int) {
+	target := len(c.currentInstructions())
+	ins := c.currentInstructions()
+	binary.BigEndian.PutUint16(ins[pos+1:], uint16(target))
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, compilationScope{SourceMap: make(map[int]Position)})
+	c.scopeIndex++
+	c.symbolTable = newSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() compilationScope {
+	scope := c.scopes[c.scopeIndex]
+	c.scopes = c.scopes[:c.scopeIndex]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+	return scope
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	c.currentPos = node.Pos()
+	switch n := node.(type) {
+	case *ast.Program:
+		for _, stmt := range n.Statements {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+	case *ast.VarDecl:
+		if err := c.Compile(n.Value); err != nil {
+			return err
+		}
+		sym := c.symbolTable.Define(n.Name)
+		c.emitBinding(sym)
+	case *ast.Assign:
+		if err := c.Compile(n.Value); err != nil {
+			return err
+		}
+		sym, ok := c.symbolTable.Resolve(n.Name)
+		if !ok {
+			sym = c.symbolTable.Define(n.Name)
+		} else if err := c.checkCapture(n.Name, sym); err != nil {
+			return err
+		}
+		c.emitBinding(sym)
+	case *ast.Var:
+		sym, ok := c.symbolTable.Resolve(n.Name)
+		if !ok {
+			return fmt.Errorf("compile error: undefined variable %s", n.Name)
+		}
+		if err := c.checkCapture(n.Name, sym); err != nil {
+			return err
+		}
+		if sym.Scope == localScope {
+			c.emit(OpGetLocal, sym.Index)
+		} else {
+			c.emit(OpGetGlobal, sym.Index)
+		}
+	case *ast.Literal:
+		c.emit(OpConstant, c.addConstant(n.Value))
+	case *ast.StrLit:
+		c.emit(OpConstant, c.addConstant(n.Value))
+	case *ast.BinOp:
+		if err := c.Compile(n.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(n.Right); err != nil {
+			return err
+		}
+		switch n.Op {
+		case "+":
+			c.emit(OpAdd)
+		case "-":
+			c.emit(OpSub)
+		case "*":
+			c.emit(OpMul)
+		case "/":
+			c.emit(OpDiv)
+		case "^":
+			c.emit(OpPow)
+		default:
+			return fmt.Errorf("compile error: unknown operator %s", n.Op)
+		}
+	case *ast.IfStmt:
+		if err := c.Compile(n.Condition); err != nil {
+			return err
+		}
+		jumpFalsePos := c.emit(OpJumpFalse, 0)
+		for _, stmt := range n.Then {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		jumpPos := c.emit(OpJump, 0)
+		c.patchJump(jumpFalsePos)
+		for _, stmt := range n.Else {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		c.patchJump(jumpPos)
+	case *ast.WhileStmt:
+		c.loops = append(c.loops, &loop{})
+		condPos := len(c.currentInstructions())
+		if err := c.Compile(n.Condition); err != nil {
+			return err
+		}
+		exitJump := c.emit(OpJumpFalse, 0)
+		for _, stmt := range n.Body {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		c.emit(OpJump, condPos)
+		c.patchJump(exitJump)
+		lp := c.loops[len(c.loops)-1]
+		for _, b := range lp.Breaks {
+			c.patchJump(b)
+		}
+		c.loops = c.loops[:len(c.loops)-1]
+	case *ast.ForStmt:
+		if err := c.Compile(n.Start); err != nil {
+			return err
+		}
+		sym := c.symbolTable.Define(n.Var)
+		c.emitBinding(sym)
+		c.loops = append(c.loops, &loop{})
+		condPos := len(c.currentInstructions())
+		c.emitRead(sym)
+		if err := c.Compile(n.End); err != nil {
+			return err
+		}
+		c.emit(OpLessThan)
+		exitJump := c.emit(OpJumpFalse, 0)
+		for _, stmt := range n.Body {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		c.emitRead(sym)
+		c.emit(OpConstant, c.addConstant(float64(1)))
+		c.emit(OpAdd)
+		c.emitBinding(sym)
+		c.emit(OpJump, condPos)
+		c.patchJump(exitJump)
+		lp := c.loops[len(c.loops)-1]
+		for _, b := range lp.Breaks {
+			c.patchJump(b)
+		}
+		c.loops = c.loops[:len(c.loops)-1]
+	case *ast.FuncDef:
+		sym := c.symbolTable.Define(n.Name)
+		c.enterScope()
+		for _, param := range n.Params {
+			c.symbolTable.Define(param)
+		}
+		for _, stmt := range n.Body {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		c.emit(OpReturn)
+		numLocals := c.symbolTable.numDefinitions
+		scope := c.leaveScope()
+		fn := &CompiledFunction{
+			Instructions: scope.Instructions,
+			SourceMap:    scope.SourceMap,
+			NumParams:    len(n.Params),
+			NumLocals:    numLocals,
+		}
+		c.emit(OpConstant, c.addConstant(fn))
+		c.emitBinding(sym)
+	case *ast.FuncLit:
+		c.enterScope()
+		for _, param := range n.Params {
+			c.symbolTable.Define(param)
+		}
+		for _, stmt := range n.Body {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		c.emit(OpReturn)
+		numLocals := c.symbolTable.numDefinitions
+		scope := c.leaveScope()
+		fn := &CompiledFunction{
+			Instructions: scope.Instructions,
+			SourceMap:    scope.SourceMap,
+			NumParams:    len(n.Params),
+			NumLocals:    numLocals,
+		}
+		c.emit(OpConstant, c.addConstant(fn))
+	case *ast.ReturnStmt:
+		if n.Expr == nil {
+			c.emit(OpReturn)
+			break
+		}
+		if err := c.Compile(n.Expr); err != nil {
+			return err
+		}
+		c.emit(OpReturnValue)
+	case *ast.FuncCall:
+		sym, ok := c.symbolTable.Resolve(n.Name)
+		if !ok {
+			c.emit(OpGetBuiltin, 0)
+		} else {
+			if err := c.checkCapture(n.Name, sym); err != nil {
+				return err
+			}
+			c.emitRead(sym)
+		}
+		for _, arg := range n.Args {
+			if err := c.Compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(OpCall, len(n.Args))
+	case *ast.WriteStmt:
+		if err := c.Compile(n.Expr); err != nil {
+			return err
+		}
+		c.emit(OpPrint)
+	case *ast.ImportStmt:
+		// Module loading is a runtime concern handled before the VM
+		// starts; nothing to emit here yet.
+	case *ast.Embedded:
+		return fmt.Errorf("compile error: embedded blocks are not supported under --vm yet")
+	default:
+		return fmt.Errorf("compile error: unknown node type %T", node)
+	}
+	return nil
+}
+
+// checkCapture rejects a reference to sym if it's local but not owned
+// by the symbolTable currently being compiled (i.e. it belongs to some
+// enclosing function's locals, not the function currently being
+// compiled). OpGetLocal/OpSetLocal always index off the *current*
+// frame's basePointer, and there's no upvalue capture to make that
+// correct for a variable that actually lives in an outer call's frame
+// -- so instead of silently emitting bytecode that reads/writes the
+// wrong slot, refuse to compile it. Globals are unaffected: they're
+// read by value, not frame-relative.
+func (c *Compiler) checkCapture(name string, sym symbol) error {
+	if sym.Scope == localScope && !c.symbolTable.own(name) {
+		return fmt.Errorf("compile error: nested function closes over %q from an enclosing function call, which --vm doesn't support yet (only globals can be captured by a nested fn)", name)
+	}
+	return nil
+}
+
+func (c *Compiler) emitBinding(sym symbol) {
+	if sym.Scope == localScope {
+		c.emit(OpSetLocal, sym.Index)
+	} else {
+		c.emit(OpSetGlobal, sym.Index)
+	}
+}
+
+func (c *Compiler) emitRead(sym symbol) {
+	if sym.Scope == localScope {
+		c.emit(OpGetLocal, sym.Index)
+	} else {
+		c.emit(OpGetGlobal, sym.Index)
+	}
+}
@@ -0,0 +1,117 @@
+// internal/interpreter/embedded.go - Pluggable Embedded code backends
+//
+// Replaces the old hard-coded "shell out to python" handling of
+// *ast.Embedded with a registry of EmbeddedRunner implementations keyed
+// by language name, each run with a timeout and under exec.CommandContext
+// so a runaway script can't hang the interpreter forever. A Scope only
+// runs a language if it's listed in AllowedEmbedded, since embedded code
+// executes arbitrary commands on the host.
+
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultEmbeddedTimeout bounds how long a single Embedded block may run
+// before it's killed, for runners that don't set their own.
+const DefaultEmbeddedTimeout = 5 * time.Second
+
+// EmbeddedRunner executes one Embedded block's code under a language
+// backend, writing its output to stdout/stderr and reading stdin if the
+// code asks for input. It must respect timeout, returning once it
+// elapses even if the underlying process doesn't exit cleanly.
+type EmbeddedRunner interface {
+	Run(code string, stdin io.Reader, stdout, stderr io.Writer, timeout time.Duration) error
+}
+
+// embeddedRunners holds the process-wide registry of language backends.
+// It's a package-level map (rather than per-Scope) because backends are
+// a property of the build, not of any one script's runtime state; which
+// languages a given script may actually use is controlled separately by
+// Scope.AllowedEmbedded.
+var embeddedRunners = map[string]EmbeddedRunner{
+	"python": commandRunner{bin: "python3"},
+	"sh":     commandRunner{bin: "sh"},
+	"lua":    commandRunner{bin: "lua"},
+}
+
+// RegisterEmbedded makes lang resolvable by *ast.Embedded blocks,
+// replacing any existing runner for that name. Intended for init()-time
+// setup, e.g. to swap in a different python interpreter.
+func RegisterEmbedded(lang string, runner EmbeddedRunner) {
+	embeddedRunners[lang] = runner
+}
+
+// commandRunner is an EmbeddedRunner that feeds code to bin on stdin
+// (via a temp-free -c/script-on-stdin invocation, so nothing is ever
+// written to disk) under exec.CommandContext, and kills the whole
+// process group if it outlives timeout.
+type commandRunner struct {
+	bin  string
+	args []string
+}
+
+func (r commandRunner) Run(code string, stdin io.Reader, stdout, stderr io.Writer, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultEmbeddedTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.bin, r.args...)
+	cmd.Stdin = bytes.NewBufferString(code)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Run the interpreter in its own process group so a timeout kills
+	// every child it may have spawned, not just the direct process.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("embedded %s: %w", r.bin, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("embedded %s: %w", r.bin, err)
+		}
+		return nil
+	case <-ctx.Done():
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("embedded %s: timed out after %s", r.bin, timeout)
+	}
+}
+
+// runEmbedded looks up lang in the registry and the scope's allow-list,
+// runs code, and returns its captured stdout as the value of the
+// Embedded expression.
+func runEmbedded(scope *Scope, lang, code string) (string, error) {
+	if !scope.allowedEmbedded[lang] {
+		return "", fmt.Errorf("embedded %q blocks are not allowed in this scope", lang)
+	}
+	runner, ok := embeddedRunners[lang]
+	if !ok {
+		return "", fmt.Errorf("no embedded runner registered for %q", lang)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := runner.Run(code, nil, &stdout, &stderr, DefaultEmbeddedTimeout); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -5,24 +5,125 @@ package interpreter
 import (
 	"fmt"
 	"math"
-	"os"
-	"os/exec"
+	"strconv"
+	"strings"
 
 	"nula-backend/internal/ast"
 )
 
+// Frame is one entry of the call stack captured in a RuntimeError, so
+// users can see which function call led to the failure.
+type Frame struct {
+	FuncName string
+	Pos      ast.Position
+}
+
+// RuntimeError is returned for any failure during eval. It carries the
+// source position of the node that failed and the call stack at that
+// point, so callers can print e.g. "file.nula:12:5: undefined variable x"
+// instead of an untyped message.
+type RuntimeError struct {
+	Pos   ast.Position
+	Msg   string
+	Stack []Frame
+}
+
+func (e *RuntimeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Pos, e.Msg)
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		f := e.Stack[i]
+		fmt.Fprintf(&b, "\n\tat %s (%s)", f.FuncName, f.Pos)
+	}
+	return b.String()
+}
+
+// Closure is a first-class function value: the params and body of the
+// FuncDef or FuncLit that produced it, plus the Scope it closed over.
+// Both named (`fn f(x){...}`) and anonymous (`fn(x){...}`) functions
+// become one of these, so they're interchangeable as values.
+type Closure struct {
+	Name   string // "" for a FuncLit
+	Params []string
+	Body   []ast.Node
+	Env    *Scope
+}
+
+// returnSignal is how a *ast.ReturnStmt unwinds back to the call it
+// returned from: it's propagated as an error through the normal eval
+// error path and caught in callClosure.
+type returnSignal struct {
+	Value interface{}
+}
+
+func (r *returnSignal) Error() string { return "return outside of a function call" }
+
 type Scope struct {
 	vars   map[string]interface{}
 	parent *Scope
-	funcs  map[string]*ast.FuncDef
+
+	moduleGetter ModuleGetter
+	moduleCache  *map[string]*ModuleValue
+
+	// allowedEmbedded is the set of languages this scope (and its
+	// descendants, since a child inherits its parent's) may run
+	// *ast.Embedded blocks in. Empty by default, since embedded code
+	// runs arbitrary commands on the host.
+	allowedEmbedded map[string]bool
+}
+
+// ScopeOption configures a Scope at construction time; see
+// SetModuleGetter and SetAllowFileImport.
+type ScopeOption func(*Scope)
+
+// SetModuleGetter makes the scope (and its descendants, since a child
+// scope inherits its parent's module getter) resolve ImportStmts
+// through g instead of erroring with "no module getter configured".
+func SetModuleGetter(g ModuleGetter) ScopeOption {
+	return func(s *Scope) { s.moduleGetter = g }
 }
 
-func NewScope(parent *Scope) *Scope {
-	return &Scope{
+// SetAllowFileImport toggles file-based imports on the scope's module
+// getter, if it's a *FileModuleGetter. It's a no-op otherwise.
+func SetAllowFileImport(allow bool) ScopeOption {
+	return func(s *Scope) {
+		if fg, ok := s.moduleGetter.(*FileModuleGetter); ok {
+			fg.AllowFileImport = allow
+		}
+	}
+}
+
+// AllowEmbedded adds langs to the scope's allow-list for *ast.Embedded
+// blocks. Embedded code is otherwise refused, since it runs arbitrary
+// commands on the host.
+func AllowEmbedded(langs ...string) ScopeOption {
+	return func(s *Scope) {
+		if s.allowedEmbedded == nil {
+			s.allowedEmbedded = make(map[string]bool)
+		}
+		for _, lang := range langs {
+			s.allowedEmbedded[lang] = true
+		}
+	}
+}
+
+func NewScope(parent *Scope, opts ...ScopeOption) *Scope {
+	s := &Scope{
 		vars:   make(map[string]interface{}),
 		parent: parent,
-		funcs:  make(map[string]*ast.FuncDef),
 	}
+	if parent != nil {
+		s.moduleGetter = parent.moduleGetter
+		s.moduleCache = parent.moduleCache
+		s.allowedEmbedded = parent.allowedEmbedded
+	} else {
+		cache := make(map[string]*ModuleValue)
+		s.moduleCache = &cache
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Scope) Get(name string) interface{} {
@@ -39,23 +140,10 @@ func (s *Scope) Set(name string, val interface{}) {
 	s.vars[name] = val
 }
 
-func (s *Scope) DefineFunc(def *ast.FuncDef) {
-	s.funcs[def.Name] = def
-}
-
-func (s *Scope) GetFunc(name string) *ast.FuncDef {
-	if f, ok := s.funcs[name]; ok {
-		return f
-	}
-	if s.parent != nil {
-		return s.parent.GetFunc(name)
-	}
-	return nil
-}
-
 func Interpret(program ast.Program, scope *Scope) error {
+	var stack []Frame
 	for _, stmt := range program.Statements {
-		_, err := eval(stmt, scope)
+		_, err := eval(stmt, scope, &stack)
 		if err != nil {
 			return err
 		}
@@ -63,47 +151,85 @@ func Interpret(program ast.Program, scope *Scope) error {
 	return nil
 }
 
-func eval(node ast.Node, scope *Scope) (interface{}, error) {
+func runtimeErr(n ast.Node, stack []Frame, format string, args ...interface{}) error {
+	frames := make([]Frame, len(stack))
+	copy(frames, stack)
+	return &RuntimeError{Pos: n.Pos(), Msg: fmt.Sprintf(format, args...), Stack: frames}
+}
+
+// callClosure runs clo's body in a fresh scope rooted at clo.Env (the
+// scope it closed over, not the caller's), binds args to its params,
+// and pushes a Frame for the duration of the call so errors can report
+// a call stack. A *ast.ReturnStmt inside the body surfaces here as a
+// *returnSignal, which becomes the call's result instead of an error.
+func callClosure(clo *Closure, args []interface{}, call ast.Node, stack *[]Frame) (interface{}, error) {
+	name := clo.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if len(args) != len(clo.Params) {
+		return nil, runtimeErr(call, *stack, "wrong number of arguments to %s: want %d, got %d", name, len(clo.Params), len(args))
+	}
+	funcScope := NewScope(clo.Env)
+	for i, param := range clo.Params {
+		funcScope.Set(param, args[i])
+	}
+	*stack = append(*stack, Frame{FuncName: name, Pos: call.Pos()})
+	defer func() { *stack = (*stack)[:len(*stack)-1] }()
+	for _, stmt := range clo.Body {
+		_, err := eval(stmt, funcScope, stack)
+		if err == nil {
+			continue
+		}
+		if rs, ok := err.(*returnSignal); ok {
+			return rs.Value, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func eval(node ast.Node, scope *Scope, stack *[]Frame) (interface{}, error) {
 	switch n := node.(type) {
 	case *ast.Program:
 		var last interface{}
 		for _, stmt := range n.Statements {
 			var err error
-			last, err = eval(stmt, scope)
+			last, err = eval(stmt, scope, stack)
 			if err != nil {
 				return nil, err
 			}
 		}
 		return last, nil
 	case *ast.VarDecl:
-		val, err := eval(n.Value, scope)
+		val, err := eval(n.Value, scope, stack)
 		if err != nil {
 			return nil, err
 		}
 		scope.Set(n.Name, val)
 		return val, nil
 	case *ast.Assign:
-		val, err := eval(n.Value, scope)
+		val, err := eval(n.Value, scope, stack)
 		if err != nil {
 			return nil, err
 		}
 		scope.Set(n.Name, val)
 		return val, nil
 	case *ast.IfStmt:
-		cond, err := eval(n.Condition, scope)
+		cond, err := eval(n.Condition, scope, stack)
 		if err != nil {
 			return nil, err
 		}
 		if isTrue(cond) {
 			for _, stmt := range n.Then {
-				_, err := eval(stmt, scope)
+				_, err := eval(stmt, scope, stack)
 				if err != nil {
 					return nil, err
 				}
 			}
 		} else {
 			for _, stmt := range n.Else {
-				_, err := eval(stmt, scope)
+				_, err := eval(stmt, scope, stack)
 				if err != nil {
 					return nil, err
 				}
@@ -112,7 +238,7 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 		return nil, nil
 	case *ast.WhileStmt:
 		for {
-			cond, err := eval(n.Condition, scope)
+			cond, err := eval(n.Condition, scope, stack)
 			if err != nil {
 				return nil, err
 			}
@@ -120,7 +246,7 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 				break
 			}
 			for _, stmt := range n.Body {
-				_, err := eval(stmt, scope)
+				_, err := eval(stmt, scope, stack)
 				if err != nil {
 					return nil, err
 				}
@@ -128,11 +254,11 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 		}
 		return nil, nil
 	case *ast.ForStmt:
-		start, err := eval(n.Start, scope)
+		start, err := eval(n.Start, scope, stack)
 		if err != nil {
 			return nil, err
 		}
-		end, err := eval(n.End, scope)
+		end, err := eval(n.End, scope, stack)
 		if err != nil {
 			return nil, err
 		}
@@ -140,7 +266,7 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 		for i := s; i < e; i++ {
 			scope.Set(n.Var, i)
 			for _, stmt := range n.Body {
-				_, err := eval(stmt, scope)
+				_, err := eval(stmt, scope, stack)
 				if err != nil {
 					return nil, err
 				}
@@ -148,38 +274,57 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 		}
 		return nil, nil
 	case *ast.FuncDef:
-		scope.DefineFunc(n)
+		scope.Set(n.Name, &Closure{Name: n.Name, Params: n.Params, Body: n.Body, Env: scope})
 		return nil, nil
-	case *ast.FuncCall:
-		f := scope.GetFunc(n.Name)
-		if f == nil {
-			return nil, fmt.Errorf("undefined function %s", n.Name)
+	case *ast.FuncLit:
+		return &Closure{Params: n.Params, Body: n.Body, Env: scope}, nil
+	case *ast.ReturnStmt:
+		if n.Expr == nil {
+			return nil, &returnSignal{}
+		}
+		val, err := eval(n.Expr, scope, stack)
+		if err != nil {
+			return nil, err
 		}
+		return nil, &returnSignal{Value: val}
+	case *ast.FuncCall:
 		args := make([]interface{}, len(n.Args))
 		for i, arg := range n.Args {
-			val, err := eval(arg, scope)
+			val, err := eval(arg, scope, stack)
 			if err != nil {
 				return nil, err
 			}
 			args[i] = val
 		}
-		funcScope := NewScope(scope)
-		for i, param := range f.Params {
-			funcScope.Set(param, args[i])
-		}
-		for _, stmt := range f.Body {
-			_, err := eval(stmt, funcScope)
-			if err != nil {
-				return nil, err
+		if alias, member, ok := splitDotted(n.Name); ok {
+			modv, isMod := scope.Get(alias).(*ModuleValue)
+			if !isMod {
+				return nil, runtimeErr(n, *stack, "undefined module %s", alias)
+			}
+			if modv.Builtin != nil {
+				fn, ok := modv.Builtin.Funcs[member]
+				if !ok {
+					return nil, runtimeErr(n, *stack, "undefined function %s.%s", alias, member)
+				}
+				return fn(args)
 			}
+			clo, ok := modv.Scope.Get(member).(*Closure)
+			if !ok {
+				return nil, runtimeErr(n, *stack, "undefined function %s.%s", alias, member)
+			}
+			return callClosure(clo, args, n, stack)
+		}
+		clo, ok := scope.Get(n.Name).(*Closure)
+		if !ok {
+			return nil, runtimeErr(n, *stack, "undefined function %s", n.Name)
 		}
-		return nil, nil // Assume no return for now
+		return callClosure(clo, args, n, stack)
 	case *ast.BinOp:
-		left, err := eval(n.Left, scope)
+		left, err := eval(n.Left, scope, stack)
 		if err != nil {
 			return nil, err
 		}
-		right, err := eval(n.Right, scope)
+		right, err := eval(n.Right, scope, stack)
 		if err != nil {
 			return nil, err
 		}
@@ -192,6 +337,9 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 		case "*":
 			return l * r, nil
 		case "/":
+			if r == 0 {
+				return nil, runtimeErr(n, *stack, "division by zero")
+			}
 			return l / r, nil
 		case "^":
 			return math.Pow(l, r), nil
@@ -201,37 +349,44 @@ func eval(node ast.Node, scope *Scope) (interface{}, error) {
 	case *ast.StrLit:
 		return n.Value, nil
 	case *ast.Var:
+		if alias, member, ok := splitDotted(n.Name); ok {
+			modv, isMod := scope.Get(alias).(*ModuleValue)
+			if !isMod {
+				return nil, runtimeErr(n, *stack, "undefined module %s", alias)
+			}
+			if modv.Scope == nil {
+				return nil, runtimeErr(n, *stack, "%s.%s is a function, not a value", alias, member)
+			}
+			val := modv.Scope.Get(member)
+			if val == nil {
+				return nil, runtimeErr(n, *stack, "undefined variable %s.%s", alias, member)
+			}
+			return val, nil
+		}
 		val := scope.Get(n.Name)
 		if val == nil {
-			return nil, fmt.Errorf("undefined variable %s", n.Name)
+			return nil, runtimeErr(n, *stack, "undefined variable %s", n.Name)
 		}
 		return val, nil
 	case *ast.ImportStmt:
-		fmt.Printf("Imported %s\n", n.Name)
+		modv, err := resolveModule(scope, n.Name)
+		if err != nil {
+			return nil, runtimeErr(n, *stack, "%v", err)
+		}
+		bindName := n.Name
+		if n.Alias != "" {
+			bindName = n.Alias
+		}
+		scope.Set(bindName, modv)
 		return nil, nil
 	case *ast.Embedded:
-		switch n.Lang {
-		case "python":
-			tmpFile, err := os.CreateTemp("", "embedded*.py")
-			if err != nil {
-				return nil, err
-			}
-			defer os.Remove(tmpFile.Name())
-			_, err = tmpFile.WriteString(n.Code)
-			if err != nil {
-				return nil, err
-			}
-			tmpFile.Close()
-			cmd := exec.Command("python", tmpFile.Name())
-			output, err := cmd.Output()
-			if err != nil {
-				return nil, err
-			}
-			fmt.Print(string(output))
+		out, err := runEmbedded(scope, n.Lang, n.Code)
+		if err != nil {
+			return nil, runtimeErr(n, *stack, "%v", err)
 		}
-		return nil, nil
+		return out, nil
 	case *ast.WriteStmt:
-		val, err := eval(n.Expr, scope)
+		val, err := eval(n.Expr, scope, stack)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,68 @@
+package interpreter
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"nula-backend/internal/parser"
+)
+
+// runInterpreter parses and interprets src, returning whatever it wrote
+// via "write" statements.
+func runInterpreter(t *testing.T, src string) (string, error) {
+	t.Helper()
+	prog, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	runErr := Interpret(prog, NewScope(nil))
+	os.Stdout = old
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out), runErr
+}
+
+// TestCallWithMissingArgument checks that calling a function with
+// fewer arguments than parameters is a runtime error, not a silent
+// fall-through to a same-named variable in the closure's enclosing
+// scope.
+func TestCallWithMissingArgument(t *testing.T) {
+	out, err := runInterpreter(t, "var a = 99\nfn f(a){ write a }\nf()\n")
+	if err == nil {
+		t.Fatalf("expected a wrong-number-of-arguments error, got output %q", out)
+	}
+	if !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Errorf("error %q does not mention wrong number of arguments", err.Error())
+	}
+}
+
+// TestCallWithTooManyArguments is the same check in the other
+// direction.
+func TestCallWithTooManyArguments(t *testing.T) {
+	_, err := runInterpreter(t, "fn f(a){ write a }\nf(1, 2)\n")
+	if err == nil {
+		t.Fatal("expected a wrong-number-of-arguments error, got nil")
+	}
+}
+
+// TestReturnValue covers the interpreter side of return statements and
+// function literals (chunk0-5): a closure's return value must reach
+// its caller.
+func TestReturnValue(t *testing.T) {
+	out, err := runInterpreter(t, "fn double(x){ return x*2 }\nwrite double(3)\n")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if strings.TrimSpace(out) != "6" {
+		t.Errorf("got %q, want %q", out, "6")
+	}
+}
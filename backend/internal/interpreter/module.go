@@ -0,0 +1,164 @@
+// internal/interpreter/module.go - Pluggable module system
+//
+// Replaces the old ast.ImportStmt stub (which just printed "Imported X")
+// with real imports: a ModuleGetter resolves an import name to either a
+// SourceModule (a .nula file under ImportDir) or a BuiltinModule (Go
+// functions registered ahead of time, e.g. math.sqrt). Loaded modules
+// are cached by path so re-importing the same file is a no-op and
+// import cycles don't recurse forever.
+
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nula-backend/internal/ast"
+	"nula-backend/internal/parser"
+)
+
+// Module is either a SourceModule or a BuiltinModule.
+type Module interface {
+	isModule()
+}
+
+// BuiltinFunc is a Go-implemented function exposed to Nula code through
+// a BuiltinModule.
+type BuiltinFunc func(args []interface{}) (interface{}, error)
+
+// BuiltinModule is a module implemented in Go, e.g. "math" or "io".
+type BuiltinModule struct {
+	Name  string
+	Funcs map[string]BuiltinFunc
+}
+
+func (*BuiltinModule) isModule() {}
+
+// SourceModule is a module loaded and parsed from a .nula file.
+type SourceModule struct {
+	Path    string
+	Program ast.Program
+}
+
+func (*SourceModule) isModule() {}
+
+// ModuleGetter resolves the name inside `::name::` or `<name>` to a
+// Module.
+type ModuleGetter interface {
+	Get(name string) (Module, error)
+}
+
+// ModuleValue is the runtime value an ImportStmt binds: either the
+// evaluated scope of a SourceModule or a BuiltinModule's function set.
+// Members are looked up as alias.name.
+type ModuleValue struct {
+	Name    string
+	Scope   *Scope
+	Builtin *BuiltinModule
+}
+
+// FileModuleGetter is the default ModuleGetter: builtin modules
+// registered with RegisterBuiltin take priority, and anything else is
+// resolved to a file named <name><ext> under ImportDir, if
+// AllowFileImport is set.
+type FileModuleGetter struct {
+	ImportDir       string
+	ImportFileExt   []string
+	AllowFileImport bool
+
+	builtins map[string]*BuiltinModule
+}
+
+// NewFileModuleGetter returns a FileModuleGetter rooted at importDir,
+// resolving ".nula" files. File imports are disabled by default; call
+// SetAllowFileImport(true) on the Scope using it, or set
+// AllowFileImport directly.
+func NewFileModuleGetter(importDir string) *FileModuleGetter {
+	return &FileModuleGetter{
+		ImportDir:     importDir,
+		ImportFileExt: []string{".nula"},
+		builtins:      make(map[string]*BuiltinModule),
+	}
+}
+
+// RegisterBuiltin makes mod resolvable by its Name.
+func (g *FileModuleGetter) RegisterBuiltin(mod *BuiltinModule) {
+	g.builtins[mod.Name] = mod
+}
+
+func (g *FileModuleGetter) Get(name string) (Module, error) {
+	if mod, ok := g.builtins[name]; ok {
+		return mod, nil
+	}
+	if !g.AllowFileImport {
+		return nil, fmt.Errorf("module %q not found (file imports are disabled)", name)
+	}
+	for _, ext := range g.ImportFileExt {
+		path := filepath.Join(g.ImportDir, name+ext)
+		code, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		prog, err := parser.ParseFile(path, string(code))
+		if err != nil {
+			return nil, err
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		return &SourceModule{Path: abs, Program: prog}, nil
+	}
+	return nil, fmt.Errorf("module %q not found under %s", name, g.ImportDir)
+}
+
+// resolveModule imports name into scope, consulting and populating the
+// scope's module cache so the same absolute path is never evaluated
+// twice.
+func resolveModule(scope *Scope, name string) (*ModuleValue, error) {
+	if scope.moduleGetter == nil {
+		return nil, fmt.Errorf("no module getter configured")
+	}
+	mod, err := scope.moduleGetter.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	switch m := mod.(type) {
+	case *BuiltinModule:
+		return &ModuleValue{Name: name, Builtin: m}, nil
+	case *SourceModule:
+		if scope.moduleCache != nil {
+			if cached, ok := (*scope.moduleCache)[m.Path]; ok {
+				return cached, nil
+			}
+		}
+		modScope := NewScope(nil)
+		modScope.moduleGetter = scope.moduleGetter
+		modScope.moduleCache = scope.moduleCache
+		// Reserve the cache slot before evaluating the module body so a
+		// cycle (A imports B imports A) resolves to the in-progress
+		// module instead of recursing forever.
+		mv := &ModuleValue{Name: name, Scope: modScope}
+		if scope.moduleCache != nil {
+			(*scope.moduleCache)[m.Path] = mv
+		}
+		if err := Interpret(m.Program, modScope); err != nil {
+			return nil, err
+		}
+		return mv, nil
+	default:
+		return nil, fmt.Errorf("module %q: unknown module kind %T", name, mod)
+	}
+}
+
+// splitDotted splits "alias.name" into its two parts. Identifiers
+// without a "." (the common case) report ok=false.
+func splitDotted(name string) (alias, member string, ok bool) {
+	idx := strings.Index(name, ".")
+	if idx < 0 {
+		return "", "", false
+	}
+	return name[:idx], name[idx+1:], true
+}
@@ -0,0 +1,158 @@
+package interpreter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nula-backend/internal/parser"
+)
+
+// writeModule writes name.nula (src) into dir.
+func writeModule(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+".nula"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write %s.nula: %v", name, err)
+	}
+}
+
+// captureStdout runs fn with os.Stdout redirected, returning whatever
+// it wrote.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	runErr := fn()
+	os.Stdout = old
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out), runErr
+}
+
+// runImporting parses and interprets src with a scope whose
+// FileModuleGetter is rooted at dir with file imports allowed,
+// returning whatever it wrote via "write" statements.
+func runImporting(t *testing.T, dir, src string) (string, error) {
+	t.Helper()
+	prog, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	getter := NewFileModuleGetter(dir)
+	scope := NewScope(nil, SetModuleGetter(getter), SetAllowFileImport(true))
+	return captureStdout(t, func() error { return Interpret(prog, scope) })
+}
+
+// TestFileImportAndAlias covers the common case: importing a .nula
+// file under ImportDir and calling one of its functions through the
+// alias bound by "as".
+func TestFileImportAndAlias(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "util", "fn double(x) {\n    return x * 2\n}\n")
+
+	out, err := runImporting(t, dir, "::util:: as u\nwrite u.double(3)\n")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := strings.TrimSpace(out); got != "6" {
+		t.Errorf("got %q, want %q", out, "6")
+	}
+}
+
+// TestFileImportWithoutAlias covers binding the module under its own
+// name when "as" is omitted.
+func TestFileImportWithoutAlias(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "util", "fn triple(x) {\n    return x * 3\n}\n")
+
+	out, err := runImporting(t, dir, "::util::\nwrite util.triple(3)\n")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := strings.TrimSpace(out); got != "9" {
+		t.Errorf("got %q, want %q", out, "9")
+	}
+}
+
+// TestModuleCachedByAbsolutePath checks that importing the same file
+// twice (here under two aliases) evaluates its body only once: the
+// module's own top-level "write" is a side effect that would show up
+// twice in the output if the cache-by-absolute-path weren't working.
+func TestModuleCachedByAbsolutePath(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "once", "write \"loaded\"\n")
+
+	src := "::once:: as a\n::once:: as b\nwrite \"done\"\n"
+	out, err := runImporting(t, dir, src)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := strings.TrimSpace(out); got != "loaded\ndone" {
+		t.Errorf("got %q, want %q (second import should reuse the cached module, not re-evaluate it)", out, "loaded\ndone")
+	}
+}
+
+// TestImportCycleDoesNotHang checks that a importing b importing a
+// back resolves instead of recursing forever: resolveModule caches
+// the in-progress ModuleValue before evaluating the module body, so
+// the back-reference in b sees that stub instead of re-entering a's
+// import.
+func TestImportCycleDoesNotHang(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "a", "::b::\nvar x = 1\n")
+	writeModule(t, dir, "b", "::a::\nvar y = 2\n")
+
+	if _, err := runImporting(t, dir, "::a::\nwrite 1\n"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+// TestBuiltinModule covers the other half of ModuleGetter: a module
+// implemented in Go rather than loaded from a file.
+func TestBuiltinModule(t *testing.T) {
+	prog, err := parser.Parse("::math:: as m\nwrite m.sqrt(9)\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	getter := NewFileModuleGetter(t.TempDir())
+	getter.RegisterBuiltin(&BuiltinModule{
+		Name: "math",
+		Funcs: map[string]BuiltinFunc{
+			"sqrt": func(args []interface{}) (interface{}, error) {
+				return args[0], nil
+			},
+		},
+	})
+	scope := NewScope(nil, SetModuleGetter(getter))
+
+	out, err := captureStdout(t, func() error { return Interpret(prog, scope) })
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := strings.TrimSpace(out); got != "9" {
+		t.Errorf("got %q, want %q", out, "9")
+	}
+}
+
+// TestFileImportDisabledByDefault checks that a FileModuleGetter
+// refuses file imports unless AllowFileImport is set, even when the
+// file exists.
+func TestFileImportDisabledByDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "util", "fn double(x) {\n    return x * 2\n}\n")
+
+	prog, err := parser.Parse("::util::\nwrite 1\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	scope := NewScope(nil, SetModuleGetter(NewFileModuleGetter(dir)))
+	if _, err := captureStdout(t, func() error { return Interpret(prog, scope) }); err == nil {
+		t.Fatal("expected an error since file imports are disabled by default")
+	}
+}
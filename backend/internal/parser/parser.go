@@ -1,4 +1,10 @@
 // internal/parser/parser.go - Parser implementation
+//
+// Tokenizes and parses Nula source into an ast.Program. Positions are
+// tracked through tokenization and attached to every node produced, and
+// parse failures are collected as *ParseError values in Parser.errors
+// instead of panicking, so a single run can report more than one
+// mistake (mirrors go/parser's error list behavior).
 
 package parser
 
@@ -11,14 +17,40 @@ import (
 	"nula-backend/internal/ast"
 )
 
+// ParseError is one mistake found while parsing, located at Pos.
+type ParseError struct {
+	Pos ast.Position
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ParseErrors is the list of every error found during a single Parse
+// call. It implements error so callers that only care whether parsing
+// failed can keep treating the return value as a plain error.
+type ParseErrors []*ParseError
+
+func (errs ParseErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
 type Parser struct {
-	tokens []Token
-	pos    int
+	tokens   []Token
+	pos      int
+	filename string
+	errors   ParseErrors
 }
 
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   ast.Position
 }
 
 type TokenType int
@@ -30,60 +62,126 @@ const (
 	TokenOperator
 	TokenKeyword
 	TokenSymbol
+	TokenComment
 	TokenEof
 )
 
-var keywords = map[string]bool{
-	"if":    true,
-	"else":  true,
-	"while": true,
-	"for":   true,
-	"fn":    true,
-	"var":   true,
-	"write": true,
-}
+// Mode enables optional parser behavior, mirroring go/parser.Mode.
+type Mode uint
 
-func tokenize(code string) []Token {
+const (
+	// ParseComments makes Parse/ParseFile retain `@...`/`!...!` comments
+	// instead of discarding them during tokenization, returning them
+	// attached to nearby nodes via the result's Comments field.
+	ParseComments Mode = 1 << iota
+)
+
+var (
+	doubleColonImportRe = regexp.MustCompile(`^::(\w+)::`)
+	angleImportRe       = regexp.MustCompile(`^<(\w+)>`)
+	embeddedStartRe     = regexp.MustCompile(`^# =(\w+)= \[`)
+)
+
+var keywords = map[string]bool{
+	"if":     true,
+	"else":   true,
+	"while":  true,
+	"for":    true,
+	"fn":     true,
+	"var":    true,
+	"write":  true,
+	"as":     true,
+	"return": true,
+}
+
+func tokenize(code, filename string, parseComments bool) []Token {
 	var tokens []Token
 	lines := strings.Split(code, "\n")
 	inMultiComment := false
-
-	for _, line := range lines {
+	var blockStart ast.Position
+	var blockLines []string
+
+	lineIdx := 0
+lineLoop:
+	for lineIdx < len(lines) {
+		line := lines[lineIdx]
+		lineNo := lineIdx + 1
 		trimmed := strings.TrimSpace(line)
+		leadingWS := len(line) - len(strings.TrimLeft(line, " \t"))
 		if trimmed == "" {
+			lineIdx++
 			continue
 		}
 
 		if inMultiComment {
-			if strings.Contains(trimmed, "!") {
+			if idx := strings.IndexByte(trimmed, '!'); idx >= 0 {
+				blockLines = append(blockLines, trimmed[:idx])
+				if parseComments {
+					tokens = append(tokens, Token{TokenComment, strings.Join(blockLines, "\n"), blockStart})
+				}
 				inMultiComment = false
+			} else {
+				blockLines = append(blockLines, trimmed)
 			}
+			lineIdx++
 			continue
 		}
 
 		if strings.HasPrefix(trimmed, "!") {
 			inMultiComment = true
+			blockStart = ast.Position{Filename: filename, Line: lineNo, Column: leadingWS + 1}
+			blockLines = []string{strings.TrimPrefix(trimmed, "!")}
+			lineIdx++
 			continue
 		}
 
 		if strings.HasPrefix(trimmed, "@") {
+			if parseComments {
+				pos := ast.Position{Filename: filename, Line: lineNo, Column: leadingWS + 1}
+				tokens = append(tokens, Token{TokenComment, strings.TrimPrefix(trimmed, "@"), pos})
+			}
+			lineIdx++
 			continue
 		}
 
 		// Tokenize line
 		i := 0
 		for i < len(trimmed) {
+			col := leadingWS + i + 1
+			pos := ast.Position{Filename: filename, Line: lineNo, Column: col}
 			c := trimmed[i]
+			// An embedded block can appear anywhere a token can (e.g. the
+			// right side of "var out = # =sh= [...]"), not just as the
+			// first thing on a line, and its body can span multiple
+			// lines: scan forward (possibly across several lines) for
+			// the closing "]" and emit its three tokens in one shot.
+			if c == '#' {
+				if match := embeddedStartRe.FindStringSubmatch(trimmed[i:]); match != nil {
+					body, endLine, afterCol := scanEmbeddedBody(lines, lineIdx, leadingWS+i+len(match[0]))
+					tokens = append(tokens, Token{TokenKeyword, "embedded", pos})
+					tokens = append(tokens, Token{TokenIdent, match[1], pos})
+					tokens = append(tokens, Token{TokenString, body, pos})
+					if endLine != lineIdx {
+						// The block swallowed the rest of its closing
+						// line; resume tokenizing on the line after it.
+						lineIdx = endLine + 1
+						continue lineLoop
+					}
+					i = afterCol - leadingWS
+					continue
+				}
+			}
 			if isLetter(c) {
 				id := ""
-				for i < len(trimmed) && (isLetter(trimmed[i]) || isDigit(trimmed[i]) || trimmed[i] == '_') {
+				for i < len(trimmed) && (isLetter(trimmed[i]) || isDigit(trimmed[i]) || trimmed[i] == '_' ||
+					(trimmed[i] == '.' && i+1 < len(trimmed) && isLetter(trimmed[i+1]))) {
 					id += string(trimmed[i])
 					i++
 				}
 				if keywords[id] {
-					tokens = append(tokens, Token{TokenKeyword, id})
+					tokens = append(tokens, Token{TokenKeyword, id, pos})
 				} else {
-					tokens = append(tokens, Token{TokenIdent, id})
+					tokens = append(tokens, Token{TokenIdent, id, pos})
 				}
 				continue
 			}
@@ -93,7 +191,7 @@ func tokenize(code string) []Token {
 					num += string(trimmed[i])
 					i++
 				}
-				tokens = append(tokens, Token{TokenNumber, num})
+				tokens = append(tokens, Token{TokenNumber, num, pos})
 				continue
 			}
 			if c == '"' {
@@ -104,54 +202,221 @@ func tokenize(code string) []Token {
 					i++
 				}
 				i++ // skip closing "
-				tokens = append(tokens, Token{TokenString, str})
+				tokens = append(tokens, Token{TokenString, str, pos})
+				continue
+			}
+			// `::name::` import and `<name>` from-import are checked ahead
+			// of the generic operator/symbol rules below, since ':' and
+			// '<' would otherwise be swallowed as a plain symbol/operator
+			// first. Matching only the `::name::`/`<name>` prefix (rather
+			// than requiring it to span the whole line) lets a trailing
+			// "as alias" be tokenized normally afterwards.
+			if importRe := doubleColonImportRe.FindStringSubmatch(trimmed[i:]); c == ':' && importRe != nil {
+				tokens = append(tokens, Token{TokenKeyword, "import", pos})
+				tokens = append(tokens, Token{TokenIdent, importRe[1], pos})
+				i += len(importRe[0])
+				continue
+			}
+			if fromRe := angleImportRe.FindStringSubmatch(trimmed[i:]); c == '<' && fromRe != nil {
+				tokens = append(tokens, Token{TokenKeyword, "from", pos})
+				tokens = append(tokens, Token{TokenIdent, fromRe[1], pos})
+				i += len(fromRe[0])
 				continue
 			}
 			if strings.Contains("+-*/^=<>!&|", string(c)) {
-				tokens = append(tokens, Token{TokenOperator, string(c)})
+				tokens = append(tokens, Token{TokenOperator, string(c), pos})
 				i++
 				continue
 			}
 			if strings.Contains("(){}[]:;,", string(c)) {
-				tokens = append(tokens, Token{TokenSymbol, string(c)})
+				tokens = append(tokens, Token{TokenSymbol, string(c), pos})
 				i++
 				continue
 			}
-			if c == ':' && i+1 < len(trimmed) && trimmed[i+1] == ':' {
-				importName := strings.TrimPrefix(trimmed[i+2:], ":")
-				tokens = append(tokens, Token{TokenKeyword, "import"})
-				tokens = append(tokens, Token{TokenIdent, importName})
-				break // Assume whole line
-			}
-			if c == '<' && strings.HasSuffix(trimmed, ">") {
-				module := strings.Trim(trimmed, "<>")
-				tokens = append(tokens, Token{TokenKeyword, "from"})
-				tokens = append(tokens, Token{TokenIdent, module})
-				break
-			}
-			embeddedRe := regexp.MustCompile(`# =(\w+)= \[(.*)\]`)
-			if match := embeddedRe.FindStringSubmatch(trimmed); match != nil {
-				tokens = append(tokens, Token{TokenKeyword, "embedded"})
-				tokens = append(tokens, Token{TokenIdent, match[1]})
-				tokens = append(tokens, Token{TokenString, match[2]})
-				break
-			}
 			i++
 		}
+		lineIdx++
 	}
-	tokens = append(tokens, Token{TokenEof, ""})
+	eofLine := len(lines) + 1
+	tokens = append(tokens, Token{TokenEof, "", ast.Position{Filename: filename, Line: eofLine, Column: 1}})
 	return tokens
 }
 
-func Parse(code string) (ast.Program, error) {
-	p := &Parser{tokens: tokenize(code)}
-	return ast.Program{Statements: p.parseProgram()}, nil
+// scanEmbeddedBody returns the text between the "[" of an embedded block
+// (already matched on lines[startLine] up to column afterBracket) and
+// its matching closing "]", which may be on a later line, plus the
+// index of the line that "]" was found on and the column immediately
+// after it (so a caller on the same line can resume tokenizing whatever
+// follows). Finding "matching" requires tracking "[...]" nesting depth
+// and skipping brackets inside quoted strings, since the embedded
+// language (e.g. a Python list literal) can itself contain "]" before
+// the block's real end. An unterminated block consumes to EOF.
+func scanEmbeddedBody(lines []string, startLine, afterBracket int) (body string, endLine, afterCol int) {
+	var b strings.Builder
+	depth := 1
+	var quote byte
+	escape := false
+	scanLine := func(line string) (cut int, found bool) {
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			if quote != 0 {
+				switch {
+				case escape:
+					escape = false
+				case c == '\\':
+					escape = true
+				case c == quote:
+					quote = 0
+				}
+				continue
+			}
+			switch c {
+			case '"', '\'':
+				quote = c
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return i, true
+				}
+			}
+		}
+		return 0, false
+	}
+	rest := lines[startLine][afterBracket:]
+	if cut, found := scanLine(rest); found {
+		return rest[:cut], startLine, afterBracket + cut + 1
+	}
+	b.WriteString(rest)
+	for i := startLine + 1; i < len(lines); i++ {
+		if cut, found := scanLine(lines[i]); found {
+			b.WriteByte('\n')
+			b.WriteString(lines[i][:cut])
+			return b.String(), i, cut + 1
+		}
+		b.WriteByte('\n')
+		b.WriteString(lines[i])
+	}
+	last := len(lines) - 1
+	return b.String(), last, len(lines[last])
+}
+
+// Parse parses code with no associated filename. Prefer ParseFile when
+// a filename is available, since it makes resulting errors and node
+// positions actually point somewhere.
+func Parse(code string, mode ...Mode) (ast.Program, error) {
+	return ParseFile("", code, mode...)
+}
+
+// ParseFile parses code read from filename, attaching filename to every
+// position it records. By default comments are discarded during
+// tokenization, same as always; pass ParseComments to retain them on
+// the result's Comments field instead.
+func ParseFile(filename, code string, mode ...Mode) (ast.Program, error) {
+	var m Mode
+	for _, opt := range mode {
+		m |= opt
+	}
+	withComments := m&ParseComments != 0
+
+	all := tokenize(code, filename, withComments)
+	var tokens, comments []Token
+	for _, t := range all {
+		if t.Type == TokenComment {
+			comments = append(comments, t)
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+
+	p := &Parser{tokens: tokens, filename: filename}
+	stmts := p.parseProgram()
+	prog := ast.Program{Statements: stmts}
+	if len(stmts) > 0 {
+		prog.At = stmts[0].Pos()
+	}
+	if withComments {
+		prog.Comments = attachComments(comments, stmts)
+	}
+	if len(p.errors) > 0 {
+		return prog, p.errors
+	}
+	return prog, nil
+}
+
+// attachComments assigns each comment to the first statement that
+// follows it in source order, recursing into block bodies so a comment
+// inside a function or if/while/for body attaches to a statement in
+// that body rather than the enclosing one. A comment with nothing
+// after it (e.g. a trailing file comment) is dropped.
+func attachComments(comments []Token, stmts []ast.Node) ast.CommentMap {
+	if len(comments) == 0 {
+		return nil
+	}
+	anchors := collectAnchors(stmts)
+	m := make(ast.CommentMap)
+	ai := 0
+	for _, c := range comments {
+		for ai < len(anchors) && posBefore(anchors[ai].Pos(), c.Pos) {
+			ai++
+		}
+		if ai >= len(anchors) {
+			break
+		}
+		m[anchors[ai]] = append(m[anchors[ai]], &ast.Comment{Text: c.Value, Pos: c.Pos})
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func posBefore(a, b ast.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
+// collectAnchors flattens stmts and their nested block bodies into one
+// source-ordered slice of candidate comment targets.
+func collectAnchors(stmts []ast.Node) []ast.Node {
+	var out []ast.Node
+	for _, s := range stmts {
+		out = append(out, s)
+		switch n := s.(type) {
+		case *ast.IfStmt:
+			out = append(out, collectAnchors(n.Then)...)
+			out = append(out, collectAnchors(n.Else)...)
+		case *ast.WhileStmt:
+			out = append(out, collectAnchors(n.Body)...)
+		case *ast.ForStmt:
+			out = append(out, collectAnchors(n.Body)...)
+		case *ast.FuncDef:
+			out = append(out, collectAnchors(n.Body)...)
+		}
+	}
+	return out
+}
+
+func (p *Parser) errorf(format string, args ...interface{}) {
+	p.errors = append(p.errors, &ParseError{Pos: p.current().Pos, Msg: fmt.Sprintf(format, args...)})
 }
 
 func (p *Parser) parseProgram() []ast.Node {
 	var stmts []ast.Node
-	for p.current() != TokenEof {
-		stmts = append(stmts, p.parseStmt())
+	for p.currentType() != TokenEof {
+		startPos := p.pos
+		stmt := p.parseStmt()
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+		if p.pos == startPos {
+			// parseStmt made no progress (e.g. an unexpected token); skip
+			// it so a single bad token can't loop forever.
+			p.advance()
+		}
 	}
 	return stmts
 }
@@ -172,6 +437,8 @@ func (p *Parser) parseStmt() ast.Node {
 			return p.parseFuncDef()
 		case "write":
 			return p.parseWrite()
+		case "return":
+			return p.parseReturn()
 		case "import":
 			return p.parseImport()
 		case "from":
@@ -186,26 +453,37 @@ func (p *Parser) parseStmt() ast.Node {
 }
 
 func (p *Parser) parseVarDecl() ast.Node {
+	pos := p.current().Pos
 	p.advance() // var
 	name := p.expect(TokenIdent).Value
 	p.expectOp("=")
 	value := p.parseExpr()
-	return &ast.VarDecl{Name: name, Value: value}
+	n := &ast.VarDecl{Name: name, Value: value}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseAssignOrCall() ast.Node {
+	pos := p.current().Pos
 	name := p.expect(TokenIdent).Value
 	if p.currentValue() == "=" {
 		p.advance()
-		return &ast.Assign{Name: name, Value: p.parseExpr()}
+		n := &ast.Assign{Name: name, Value: p.parseExpr()}
+		n.At = pos
+		return n
 	}
 	if p.currentValue() == "(" {
-		return &ast.FuncCall{Name: name, Args: p.parseArgs()}
+		n := &ast.FuncCall{Name: name, Args: p.parseArgs()}
+		n.At = pos
+		return n
 	}
-	return &ast.Var{Name: name}
+	n := &ast.Var{Name: name}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseIf() ast.Node {
+	pos := p.current().Pos
 	p.advance() // if
 	cond := p.parseExpr()
 	p.expectSym("{")
@@ -218,19 +496,25 @@ func (p *Parser) parseIf() ast.Node {
 		els = p.parseBlock()
 		p.expectSym("}")
 	}
-	return &ast.IfStmt{Condition: cond, Then: then, Else: els}
+	n := &ast.IfStmt{Condition: cond, Then: then, Else: els}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseWhile() ast.Node {
+	pos := p.current().Pos
 	p.advance() // while
 	cond := p.parseExpr()
 	p.expectSym("{")
 	body := p.parseBlock()
 	p.expectSym("}")
-	return &ast.WhileStmt{Condition: cond, Body: body}
+	n := &ast.WhileStmt{Condition: cond, Body: body}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseFor() ast.Node {
+	pos := p.current().Pos
 	p.advance() // for
 	varName := p.expect(TokenIdent).Value
 	p.expectKeyword("in")
@@ -240,15 +524,38 @@ func (p *Parser) parseFor() ast.Node {
 	p.expectSym("{")
 	body := p.parseBlock()
 	p.expectSym("}")
-	return &ast.ForStmt{Var: varName, Start: start, End: end, Body: body}
+	n := &ast.ForStmt{Var: varName, Start: start, End: end, Body: body}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseFuncDef() ast.Node {
+	pos := p.current().Pos
 	p.advance() // fn
 	name := p.expect(TokenIdent).Value
+	params, body := p.parseParamsAndBody()
+	n := &ast.FuncDef{Name: name, Params: params, Body: body}
+	n.At = pos
+	return n
+}
+
+// parseFuncLit parses an anonymous `fn(params){body}` in expression
+// position, e.g. `var f = fn(x){ return x*2 }`.
+func (p *Parser) parseFuncLit() ast.Node {
+	pos := p.current().Pos
+	p.advance() // fn
+	params, body := p.parseParamsAndBody()
+	n := &ast.FuncLit{Params: params, Body: body}
+	n.At = pos
+	return n
+}
+
+// parseParamsAndBody parses the "(params){ body }" shared by FuncDef
+// and FuncLit.
+func (p *Parser) parseParamsAndBody() ([]string, []ast.Node) {
 	p.expectSym("(")
 	var params []string
-	for p.current() != TokenSymbol || p.currentValue() != ")" {
+	for p.currentType() != TokenSymbol || p.currentValue() != ")" {
 		params = append(params, p.expect(TokenIdent).Value)
 		if p.currentValue() == "," {
 			p.advance()
@@ -258,38 +565,79 @@ func (p *Parser) parseFuncDef() ast.Node {
 	p.expectSym("{")
 	body := p.parseBlock()
 	p.expectSym("}")
-	return &ast.FuncDef{Name: name, Params: params, Body: body}
+	return params, body
 }
 
 func (p *Parser) parseWrite() ast.Node {
+	pos := p.current().Pos
 	p.advance() // write
-	return &ast.WriteStmt{Expr: p.parseExpr()}
+	n := &ast.WriteStmt{Expr: p.parseExpr()}
+	n.At = pos
+	return n
+}
+
+func (p *Parser) parseReturn() ast.Node {
+	pos := p.current().Pos
+	p.advance() // return
+	n := &ast.ReturnStmt{}
+	n.At = pos
+	if p.currentValue() != "}" && p.currentType() != TokenEof {
+		n.Expr = p.parseExpr()
+	}
+	return n
 }
 
 func (p *Parser) parseImport() ast.Node {
+	pos := p.current().Pos
 	p.advance() // import
 	name := p.expect(TokenIdent).Value
-	return &ast.ImportStmt{Name: name}
+	n := &ast.ImportStmt{Name: name, Alias: p.parseOptionalAlias()}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseFrom() ast.Node {
+	pos := p.current().Pos
 	p.advance() // from
 	name := p.expect(TokenIdent).Value
-	// For simplicity, treat as import
-	return &ast.ImportStmt{Name: name}
+	// "from" is the token synthesized for the "<name>" surface syntax;
+	// otherwise it behaves exactly like "import".
+	n := &ast.ImportStmt{Name: name, Alias: p.parseOptionalAlias(), Angle: true}
+	n.At = pos
+	return n
+}
+
+// parseOptionalAlias consumes a trailing "as name" after an import, if
+// present, and returns the alias (or "" if there isn't one).
+func (p *Parser) parseOptionalAlias() string {
+	if p.currentValue() != "as" {
+		return ""
+	}
+	p.advance()
+	return p.expect(TokenIdent).Value
 }
 
 func (p *Parser) parseEmbedded() ast.Node {
+	pos := p.current().Pos
 	p.advance() // embedded
 	lang := p.expect(TokenIdent).Value
 	code := p.expect(TokenString).Value
-	return &ast.Embedded{Lang: lang, Code: code}
+	n := &ast.Embedded{Lang: lang, Code: code}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseBlock() []ast.Node {
 	var block []ast.Node
-	for p.currentValue() != "}" && p.current() != TokenEof {
-		block = append(block, p.parseStmt())
+	for p.currentValue() != "}" && p.currentType() != TokenEof {
+		startPos := p.pos
+		stmt := p.parseStmt()
+		if stmt != nil {
+			block = append(block, stmt)
+		}
+		if p.pos == startPos {
+			p.advance()
+		}
 	}
 	return block
 }
@@ -301,9 +649,12 @@ func (p *Parser) parseExpr() ast.Node {
 func (p *Parser) parseAdd() ast.Node {
 	left := p.parseMul()
 	for p.isOp("+") || p.isOp("-") {
+		pos := p.current().Pos
 		op := p.advance().Value
 		right := p.parseMul()
-		left = &ast.BinOp{Op: op, Left: left, Right: right}
+		n := &ast.BinOp{Op: op, Left: left, Right: right}
+		n.At = pos
+		left = n
 	}
 	return left
 }
@@ -311,9 +662,12 @@ func (p *Parser) parseAdd() ast.Node {
 func (p *Parser) parseMul() ast.Node {
 	left := p.parsePow()
 	for p.isOp("*") || p.isOp("/") {
+		pos := p.current().Pos
 		op := p.advance().Value
 		right := p.parsePow()
-		left = &ast.BinOp{Op: op, Left: left, Right: right}
+		n := &ast.BinOp{Op: op, Left: left, Right: right}
+		n.At = pos
+		left = n
 	}
 	return left
 }
@@ -321,22 +675,37 @@ func (p *Parser) parseMul() ast.Node {
 func (p *Parser) parsePow() ast.Node {
 	left := p.parsePrimary()
 	if p.isOp("^") {
+		pos := p.current().Pos
 		p.advance()
 		right := p.parsePrimary()
-		left = &ast.BinOp{Op: "^", Left: left, Right: right}
+		n := &ast.BinOp{Op: "^", Left: left, Right: right}
+		n.At = pos
+		left = n
 	}
 	return left
 }
 
 func (p *Parser) parsePrimary() ast.Node {
+	pos := p.current().Pos
 	switch p.currentType() {
 	case TokenNumber:
 		val, _ := strconv.ParseFloat(p.advance().Value, 64)
-		return &ast.Literal{Value: val}
+		n := &ast.Literal{Value: val}
+		n.At = pos
+		return n
 	case TokenString:
-		return &ast.StrLit{Value: p.advance().Value}
+		n := &ast.StrLit{Value: p.advance().Value}
+		n.At = pos
+		return n
 	case TokenIdent:
 		return p.parseAssignOrCall()
+	case TokenKeyword:
+		if p.currentValue() == "fn" {
+			return p.parseFuncLit()
+		}
+		if p.currentValue() == "embedded" {
+			return p.parseEmbedded()
+		}
 	case TokenSymbol:
 		if p.currentValue() == "(" {
 			p.advance()
@@ -345,7 +714,10 @@ func (p *Parser) parsePrimary() ast.Node {
 			return expr
 		}
 	}
-	panic(fmt.Sprintf("Unexpected token: %v", p.current()))
+	p.errorf("unexpected token %q", p.currentValue())
+	n := &ast.Literal{Value: 0}
+	n.At = pos
+	return n
 }
 
 func (p *Parser) parseArgs() []ast.Node {
@@ -361,10 +733,6 @@ func (p *Parser) parseArgs() []ast.Node {
 	return args
 }
 
-func (p *Parser) current() TokenType {
-	return p.tokens[p.pos].Type
-}
-
 func (p *Parser) currentType() TokenType {
 	return p.tokens[p.pos].Type
 }
@@ -375,34 +743,40 @@ func (p *Parser) currentValue() string {
 
 func (p *Parser) advance() Token {
 	tok := p.tokens[p.pos]
-	p.pos++
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
 	return tok
 }
 
 func (p *Parser) expect(tt TokenType) Token {
 	if p.currentType() != tt {
-		panic(fmt.Sprintf("Expected %v, got %v", tt, p.currentType()))
+		p.errorf("expected token type %v, got %q", tt, p.currentValue())
+		return p.tokens[p.pos]
 	}
 	return p.advance()
 }
 
 func (p *Parser) expectOp(op string) {
 	if !p.isOp(op) {
-		panic(fmt.Sprintf("Expected op %s, got %s", op, p.currentValue()))
+		p.errorf("expected operator %q, got %q", op, p.currentValue())
+		return
 	}
 	p.advance()
 }
 
 func (p *Parser) expectSym(sym string) {
 	if p.currentValue() != sym {
-		panic(fmt.Sprintf("Expected sym %s, got %s", sym, p.currentValue()))
+		p.errorf("expected %q, got %q", sym, p.currentValue())
+		return
 	}
 	p.advance()
 }
 
 func (p *Parser) expectKeyword(kw string) {
 	if p.currentValue() != kw {
-		panic(fmt.Sprintf("Expected keyword %s, got %s", kw, p.currentValue()))
+		p.errorf("expected keyword %q, got %q", kw, p.currentValue())
+		return
 	}
 	p.advance()
 }
@@ -411,6 +785,10 @@ func (p *Parser) isOp(op string) bool {
 	return p.currentType() == TokenOperator && p.currentValue() == op
 }
 
+func (p *Parser) current() Token {
+	return p.tokens[p.pos]
+}
+
 func isLetter(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
 }
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"testing"
+
+	"nula-backend/internal/ast"
+)
+
+// TestEmbeddedInExpressionPosition covers the request's own headline
+// example: an embedded block assigned straight into a var, which
+// requires the tokenizer to recognize "# =lang= [" mid-line (not just
+// as the first thing on a line) and parsePrimary to accept it as an
+// expression.
+func TestEmbeddedInExpressionPosition(t *testing.T) {
+	prog, err := Parse(`var out = # =sh= [echo hello]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(prog.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(prog.Statements))
+	}
+	decl, ok := prog.Statements[0].(*ast.VarDecl)
+	if !ok {
+		t.Fatalf("expected *ast.VarDecl, got %T", prog.Statements[0])
+	}
+	embedded, ok := decl.Value.(*ast.Embedded)
+	if !ok {
+		t.Fatalf("expected *ast.Embedded value, got %T", decl.Value)
+	}
+	if embedded.Lang != "sh" || embedded.Code != "echo hello" {
+		t.Errorf("got Lang=%q Code=%q, want Lang=%q Code=%q", embedded.Lang, embedded.Code, "sh", "echo hello")
+	}
+}
+
+// TestEmbeddedResumesSameLine checks that tokens after an embedded
+// block's closing "]" are still tokenized when the block ends midline.
+func TestEmbeddedResumesSameLine(t *testing.T) {
+	prog, err := Parse(`var out = # =sh= [echo hello]
+write out`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(prog.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(prog.Statements))
+	}
+	if _, ok := prog.Statements[1].(*ast.WriteStmt); !ok {
+		t.Fatalf("expected second statement to be *ast.WriteStmt, got %T", prog.Statements[1])
+	}
+}
+
+// TestEmbeddedStandaloneStatement is the pre-existing statement-level
+// usage; it must keep working unchanged.
+func TestEmbeddedStandaloneStatement(t *testing.T) {
+	prog, err := Parse(`# =sh= [echo hello]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(prog.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(prog.Statements))
+	}
+	if _, ok := prog.Statements[0].(*ast.Embedded); !ok {
+		t.Fatalf("expected *ast.Embedded, got %T", prog.Statements[0])
+	}
+}
+
+// TestEmbeddedBodyWithNestedBrackets checks that a "]" belonging to the
+// embedded language itself (a Python list literal here) doesn't cut the
+// block short; scanEmbeddedBody must track bracket depth rather than
+// stopping at the first "]".
+func TestEmbeddedBodyWithNestedBrackets(t *testing.T) {
+	prog, err := Parse(`var out = # =python= [print([1,2,3])]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	decl, ok := prog.Statements[0].(*ast.VarDecl)
+	if !ok {
+		t.Fatalf("expected *ast.VarDecl, got %T", prog.Statements[0])
+	}
+	embedded, ok := decl.Value.(*ast.Embedded)
+	if !ok {
+		t.Fatalf("expected *ast.Embedded value, got %T", decl.Value)
+	}
+	const want = "print([1,2,3])"
+	if embedded.Code != want {
+		t.Errorf("got Code=%q, want %q", embedded.Code, want)
+	}
+}
@@ -0,0 +1,150 @@
+// internal/printer/printer.go - AST pretty-printer
+//
+// Print walks an ast.Program and writes back canonical, tab-indented
+// Nula source, re-emitting any comments attached to prog.Comments
+// immediately above the node they were found before. Comments are only
+// present if the program was parsed with parser.ParseComments; Print
+// works the same either way, it just has nothing to re-emit otherwise.
+
+package printer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"nula-backend/internal/ast"
+)
+
+const indentStep = "    "
+
+// Print renders prog as canonical Nula source.
+func Print(prog ast.Program) string {
+	p := &printer{comments: prog.Comments}
+	p.block(prog.Statements, 0)
+	return p.out.String()
+}
+
+type printer struct {
+	out      strings.Builder
+	comments ast.CommentMap
+}
+
+func (p *printer) indent(depth int) {
+	p.out.WriteString(strings.Repeat(indentStep, depth))
+}
+
+func (p *printer) leadingComments(n ast.Node, depth int) {
+	for _, c := range p.comments[n] {
+		p.indent(depth)
+		if strings.Contains(c.Text, "\n") {
+			fmt.Fprintf(&p.out, "!%s!\n", c.Text)
+		} else {
+			fmt.Fprintf(&p.out, "@%s\n", c.Text)
+		}
+	}
+}
+
+func (p *printer) block(stmts []ast.Node, depth int) {
+	for _, s := range stmts {
+		p.stmt(s, depth)
+	}
+}
+
+func (p *printer) stmt(node ast.Node, depth int) {
+	p.leadingComments(node, depth)
+	p.indent(depth)
+	switch n := node.(type) {
+	case *ast.VarDecl:
+		fmt.Fprintf(&p.out, "var %s = %s\n", n.Name, p.expr(n.Value, depth))
+	case *ast.Assign:
+		fmt.Fprintf(&p.out, "%s = %s\n", n.Name, p.expr(n.Value, depth))
+	case *ast.IfStmt:
+		fmt.Fprintf(&p.out, "if %s {\n", p.expr(n.Condition, depth))
+		p.block(n.Then, depth+1)
+		if len(n.Else) > 0 {
+			p.indent(depth)
+			p.out.WriteString("} else {\n")
+			p.block(n.Else, depth+1)
+		}
+		p.indent(depth)
+		p.out.WriteString("}\n")
+	case *ast.WhileStmt:
+		fmt.Fprintf(&p.out, "while %s {\n", p.expr(n.Condition, depth))
+		p.block(n.Body, depth+1)
+		p.indent(depth)
+		p.out.WriteString("}\n")
+	case *ast.ForStmt:
+		fmt.Fprintf(&p.out, "for %s in %s..%s {\n", n.Var, p.expr(n.Start, depth), p.expr(n.End, depth))
+		p.block(n.Body, depth+1)
+		p.indent(depth)
+		p.out.WriteString("}\n")
+	case *ast.FuncDef:
+		fmt.Fprintf(&p.out, "fn %s(%s) {\n", n.Name, strings.Join(n.Params, ", "))
+		p.block(n.Body, depth+1)
+		p.indent(depth)
+		p.out.WriteString("}\n")
+	case *ast.ReturnStmt:
+		if n.Expr == nil {
+			p.out.WriteString("return\n")
+		} else {
+			fmt.Fprintf(&p.out, "return %s\n", p.expr(n.Expr, depth))
+		}
+	case *ast.ImportStmt:
+		// Re-emit whichever surface syntax produced this node ("::name::"
+		// or "<name>"); neither is a real "import"/"from" keyword, so
+		// printing either of those would produce source the parser can't
+		// read back in.
+		name := fmt.Sprintf("::%s::", n.Name)
+		if n.Angle {
+			name = fmt.Sprintf("<%s>", n.Name)
+		}
+		if n.Alias != "" {
+			fmt.Fprintf(&p.out, "%s as %s\n", name, n.Alias)
+		} else {
+			fmt.Fprintf(&p.out, "%s\n", name)
+		}
+	case *ast.Embedded:
+		fmt.Fprintf(&p.out, "# =%s= [%s]\n", n.Lang, n.Code)
+	case *ast.WriteStmt:
+		fmt.Fprintf(&p.out, "write %s\n", p.expr(n.Expr, depth))
+	default:
+		fmt.Fprintf(&p.out, "%s\n", p.expr(node, depth))
+	}
+}
+
+// expr renders node as a single expression. FuncLit is the one
+// expression with its own block body, so it takes depth to indent that
+// body correctly wherever it's embedded (e.g. the right side of a
+// VarDecl).
+func (p *printer) expr(node ast.Node, depth int) string {
+	switch n := node.(type) {
+	case *ast.BinOp:
+		return fmt.Sprintf("%s %s %s", p.expr(n.Left, depth), n.Op, p.expr(n.Right, depth))
+	case *ast.Literal:
+		return strconv.FormatFloat(n.Value, 'f', -1, 64)
+	case *ast.StrLit:
+		return strconv.Quote(n.Value)
+	case *ast.Var:
+		return n.Name
+	case *ast.FuncCall:
+		args := make([]string, len(n.Args))
+		for i, a := range n.Args {
+			args[i] = p.expr(a, depth)
+		}
+		return fmt.Sprintf("%s(%s)", n.Name, strings.Join(args, ", "))
+	case *ast.FuncLit:
+		var b strings.Builder
+		fmt.Fprintf(&b, "fn(%s) {\n", strings.Join(n.Params, ", "))
+		sub := &printer{comments: p.comments}
+		sub.block(n.Body, depth+1)
+		b.WriteString(sub.out.String())
+		b.WriteString(strings.Repeat(indentStep, depth))
+		b.WriteString("}")
+		return b.String()
+	case *ast.Embedded:
+		return fmt.Sprintf("# =%s= [%s]", n.Lang, n.Code)
+	default:
+		return fmt.Sprintf("<?%T>", node)
+	}
+}
@@ -0,0 +1,44 @@
+package printer
+
+import (
+	"testing"
+
+	"nula-backend/internal/parser"
+)
+
+// TestRoundTrip parses src, prints it, and reparses the result, checking
+// that the second parse succeeds and produces the same printed output.
+// This is the guarantee `nula fmt` depends on: printing must never
+// produce source the parser can't read back in.
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"colon import", "::util::\nwrite 1\n"},
+		{"colon import with alias", "::util:: as u\nwrite u.sqrt(4)\n"},
+		{"angle import", "<util>\nwrite 1\n"},
+		{"angle import with alias", "<util> as u\nwrite u.sqrt(4)\n"},
+		{"function and return", "fn double(x) {\n    return x * 2\n}\nwrite double(3)\n"},
+		{"embedded block as statement", "# =sh= [echo hi]\n"},
+		{"embedded block in expression position", "var out = # =sh= [echo hi]\nwrite out\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parser.Parse(tt.src)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			printed := Print(prog)
+
+			reparsed, err := parser.Parse(printed)
+			if err != nil {
+				t.Fatalf("reparse of printed output failed: %v\n--- printed ---\n%s", err, printed)
+			}
+			if again := Print(reparsed); again != printed {
+				t.Errorf("printing is not idempotent:\nfirst:\n%s\nsecond:\n%s", printed, again)
+			}
+		})
+	}
+}
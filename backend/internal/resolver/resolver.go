@@ -0,0 +1,263 @@
+// internal/resolver/resolver.go - Symbol-resolution pass
+//
+// Walks an ast.Program once before it's handed to the interpreter or
+// compiler, opening a new ast.Scope per block (FuncDef, IfStmt,
+// WhileStmt, ForStmt), binding every declaration, and resolving each
+// ast.Var/ast.FuncCall to the ast.Object that declared it. Reports
+// "undeclared name" and "redeclared in this scope" as hard errors (with
+// source positions), and unused locals as warnings.
+//
+// Top-level FuncDef and VarDecl names are hoisted into the file scope
+// in a first pass, before any body is resolved, so mutual recursion and
+// calls to a helper defined later in the file work regardless of source
+// order -- the same two-pass approach go/ast's resolver uses for
+// package-level declarations.
+//
+// This doesn't yet change how internal/interpreter looks names up at
+// runtime (it still walks its own Scope chain by name); that's planned
+// follow-up once the resolver's output is trusted. internal/compiler
+// already resolves its own locals independently via its symbolTable.
+
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"nula-backend/internal/ast"
+)
+
+// Error is one hard failure found while resolving, located at Pos.
+type Error struct {
+	Pos ast.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Errors is every hard failure found during a single Resolve call. It
+// implements error so a caller that only cares whether resolution
+// failed can keep treating the return value as a plain error.
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Warning is a non-fatal finding, currently just "declared and not
+// used" locals.
+type Warning struct {
+	Pos ast.Position
+	Msg string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Pos, w.Msg)
+}
+
+type resolver struct {
+	errors   Errors
+	warnings []*Warning
+
+	// hoisted marks top-level FuncDef/VarDecl nodes whose name was
+	// already bound by hoistFile, so stmt doesn't try (and fail) to
+	// define them a second time when it walks the body.
+	hoisted map[ast.Node]bool
+}
+
+// Resolve walks prog, binding declarations and resolving references.
+// errs is nil if nothing failed; warnings are returned regardless.
+func Resolve(prog ast.Program) (errs Errors, warnings []*Warning) {
+	r := &resolver{hoisted: make(map[ast.Node]bool)}
+	file := ast.NewScope(nil)
+	r.hoistFile(prog.Statements, file)
+	for _, stmt := range prog.Statements {
+		r.stmt(stmt, file)
+	}
+	r.checkUnused(file)
+	if len(r.errors) > 0 {
+		return r.errors, r.warnings
+	}
+	return nil, r.warnings
+}
+
+// hoistFile pre-declares every top-level FuncDef/VarDecl name in scope
+// before any top-level statement's body or value is resolved; see the
+// package doc comment.
+func (r *resolver) hoistFile(stmts []ast.Node, scope *ast.Scope) {
+	for _, stmt := range stmts {
+		switch n := stmt.(type) {
+		case *ast.FuncDef:
+			r.define(scope, ast.ObjFunc, n.Name, n.Pos())
+			r.hoisted[n] = true
+		case *ast.VarDecl:
+			r.define(scope, ast.ObjVar, n.Name, n.Pos())
+			r.hoisted[n] = true
+		}
+	}
+}
+
+func (r *resolver) stmt(node ast.Node, scope *ast.Scope) {
+	switch n := node.(type) {
+	case *ast.VarDecl:
+		if !r.hoisted[n] {
+			r.expr(n.Value, scope)
+			r.define(scope, ast.ObjVar, n.Name, n.Pos())
+		} else {
+			// Hoisting bound n.Name in file scope before its own value
+			// was resolved (so *other* top-level declarations can refer
+			// to it regardless of order); hide that binding while
+			// resolving the value itself, so "var x = x + 1" is still
+			// reported as an undeclared-name error instead of resolving
+			// to its own not-yet-assigned slot.
+			obj := scope.Objects[n.Name]
+			delete(scope.Objects, n.Name)
+			r.expr(n.Value, scope)
+			scope.Objects[n.Name] = obj
+		}
+	case *ast.Assign:
+		r.expr(n.Value, scope)
+		if scope.Lookup(n.Name) == nil {
+			// No "var" form: first assignment implicitly declares, same
+			// as the interpreter's own scope.Set behavior.
+			r.define(scope, ast.ObjVar, n.Name, n.Pos())
+		}
+	case *ast.IfStmt:
+		r.expr(n.Condition, scope)
+		thenScope := ast.NewScope(scope)
+		for _, s := range n.Then {
+			r.stmt(s, thenScope)
+		}
+		r.checkUnused(thenScope)
+		elseScope := ast.NewScope(scope)
+		for _, s := range n.Else {
+			r.stmt(s, elseScope)
+		}
+		r.checkUnused(elseScope)
+	case *ast.WhileStmt:
+		r.expr(n.Condition, scope)
+		body := ast.NewScope(scope)
+		for _, s := range n.Body {
+			r.stmt(s, body)
+		}
+		r.checkUnused(body)
+	case *ast.ForStmt:
+		r.expr(n.Start, scope)
+		r.expr(n.End, scope)
+		body := ast.NewScope(scope)
+		r.define(body, ast.ObjVar, n.Var, n.Pos())
+		for _, s := range n.Body {
+			r.stmt(s, body)
+		}
+		r.checkUnused(body)
+	case *ast.FuncDef:
+		if !r.hoisted[n] {
+			r.define(scope, ast.ObjFunc, n.Name, n.Pos())
+		}
+		fnScope := ast.NewScope(scope)
+		for _, param := range n.Params {
+			r.define(fnScope, ast.ObjParam, param, n.Pos())
+		}
+		for _, s := range n.Body {
+			r.stmt(s, fnScope)
+		}
+		r.checkUnused(fnScope)
+	case *ast.ImportStmt:
+		name := n.Name
+		if n.Alias != "" {
+			name = n.Alias
+		}
+		r.define(scope, ast.ObjImport, name, n.Pos())
+	case *ast.WriteStmt:
+		r.expr(n.Expr, scope)
+	case *ast.ReturnStmt:
+		if n.Expr != nil {
+			r.expr(n.Expr, scope)
+		}
+	case *ast.Embedded:
+		// No identifiers to resolve.
+	default:
+		r.expr(node, scope)
+	}
+}
+
+func (r *resolver) expr(node ast.Node, scope *ast.Scope) {
+	switch n := node.(type) {
+	case *ast.Var:
+		obj := r.lookupModuleAware(n.Name, n.Pos(), scope)
+		if obj != nil {
+			obj.Used = true
+			n.Resolved = obj
+		}
+	case *ast.FuncCall:
+		for _, arg := range n.Args {
+			r.expr(arg, scope)
+		}
+		obj := r.lookupModuleAware(n.Name, n.Pos(), scope)
+		if obj != nil {
+			obj.Used = true
+			n.Resolved = obj
+		}
+	case *ast.BinOp:
+		r.expr(n.Left, scope)
+		r.expr(n.Right, scope)
+	case *ast.FuncLit:
+		fnScope := ast.NewScope(scope)
+		for _, param := range n.Params {
+			r.define(fnScope, ast.ObjParam, param, n.Pos())
+		}
+		for _, s := range n.Body {
+			r.stmt(s, fnScope)
+		}
+		r.checkUnused(fnScope)
+	case *ast.Literal, *ast.StrLit:
+		// No identifiers.
+	}
+}
+
+// lookupModuleAware resolves "name" or, for a dotted "alias.member"
+// reference, just the alias part (the member itself lives in the
+// module's own scope, which this pass never sees). A lookup failure is
+// reported as a hard error and nil is returned.
+func (r *resolver) lookupModuleAware(name string, pos ast.Position, scope *ast.Scope) *ast.Object {
+	lookupName := name
+	if idx := strings.IndexByte(name, '.'); idx >= 0 {
+		lookupName = name[:idx]
+	}
+	obj := scope.Lookup(lookupName)
+	if obj == nil {
+		r.errorf(pos, "undeclared name: %s", lookupName)
+		return nil
+	}
+	return obj
+}
+
+func (r *resolver) define(scope *ast.Scope, kind ast.ObjKind, name string, pos ast.Position) {
+	obj := &ast.Object{Kind: kind, Name: name, Pos: pos}
+	if alt := scope.Insert(obj); alt != nil {
+		r.errorf(pos, "%s redeclared in this scope (previous declaration at %s)", name, alt.Pos)
+	}
+}
+
+func (r *resolver) errorf(pos ast.Position, format string, args ...interface{}) {
+	r.errors = append(r.errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// checkUnused warns about every var/param declared in scope (not its
+// outer scopes) that was never read.
+func (r *resolver) checkUnused(scope *ast.Scope) {
+	for _, obj := range scope.Objects {
+		if (obj.Kind == ast.ObjVar || obj.Kind == ast.ObjParam) && !obj.Used {
+			r.warnings = append(r.warnings, &Warning{
+				Pos: obj.Pos,
+				Msg: fmt.Sprintf("%s %s declared and not used", obj.Kind, obj.Name),
+			})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package resolver
+
+import (
+	"testing"
+
+	"nula-backend/internal/parser"
+)
+
+// TestCallToLaterDefinedFunction covers mutual recursion and calling a
+// helper declared below the caller in source order: Resolve must hoist
+// top-level FuncDef names before walking any body, rather than failing
+// with "undeclared name" on a purely forward reference.
+func TestCallToLaterDefinedFunction(t *testing.T) {
+	prog, err := parser.Parse("fn a(){ b() }\nfn b(){ write 1 }\na()\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if errs, _ := Resolve(prog); errs != nil {
+		t.Fatalf("Resolve errored on a forward reference: %v", errs)
+	}
+}
+
+// TestMutualRecursion checks the same hoist with two functions that
+// each call the other, which only resolves if both names are bound
+// before either body is walked.
+func TestMutualRecursion(t *testing.T) {
+	prog, err := parser.Parse("fn isEven(n){ if n { return isOdd(n) }\n return 1 }\nfn isOdd(n){ return isEven(n) }\nisEven(4)\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if errs, _ := Resolve(prog); errs != nil {
+		t.Fatalf("Resolve errored on mutual recursion: %v", errs)
+	}
+}
+
+// TestUndeclaredNameStillErrors makes sure hoisting didn't loosen
+// resolution generally: a genuinely undeclared name must still fail.
+func TestUndeclaredNameStillErrors(t *testing.T) {
+	prog, err := parser.Parse("write nope\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	errs, _ := Resolve(prog)
+	if errs == nil {
+		t.Fatal("expected an undeclared name error, got nil")
+	}
+}
+
+// TestVarSelfReferenceStillErrors makes sure hoisting a top-level var's
+// name for forward references by other declarations doesn't also let
+// its own initializer see that not-yet-assigned name.
+func TestVarSelfReferenceStillErrors(t *testing.T) {
+	prog, err := parser.Parse("var x = x + 1\nwrite x\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	errs, _ := Resolve(prog)
+	if errs == nil {
+		t.Fatal("expected an undeclared name error for the self-reference, got nil")
+	}
+}
+
+// TestRedeclaredTopLevelNameStillErrors makes sure hoisting doesn't
+// mask a genuine redeclaration: two top-level functions with the same
+// name must still be reported once (by the hoist pass), not silently
+// accepted.
+func TestRedeclaredTopLevelNameStillErrors(t *testing.T) {
+	prog, err := parser.Parse("fn f(){ write 1 }\nfn f(){ write 2 }\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	errs, _ := Resolve(prog)
+	if errs == nil {
+		t.Fatal("expected a redeclared name error, got nil")
+	}
+}
@@ -0,0 +1,248 @@
+// internal/vm/vm.go - Stack-based bytecode VM
+//
+// Executes the compiler.Bytecode produced by internal/compiler. This
+// is the --vm execution path: a straight-line stack machine with a
+// frame stack for function calls, read instead of the recursive
+// tree-walk in internal/interpreter.
+
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"nula-backend/internal/compiler"
+)
+
+const (
+	stackSize   = 2048
+	globalsSize = 65536
+	maxFrames   = 1024
+)
+
+// Frame is one activation record on the call stack: the function
+// being executed, the current instruction pointer into it, and the
+// stack slot its locals start at.
+type Frame struct {
+	fn          *compiler.CompiledFunction
+	ip          int
+	basePointer int
+}
+
+type VM struct {
+	constants    []interface{}
+	instructions []byte
+
+	stack []interface{}
+	sp    int
+
+	globals []interface{}
+
+	frames      []*Frame
+	framesIndex int
+}
+
+func New(bc *compiler.Bytecode) *VM {
+	mainFrame := &Frame{
+		fn:          &compiler.CompiledFunction{Instructions: bc.Instructions, SourceMap: bc.SourceMap},
+		ip:          -1,
+		basePointer: 0,
+	}
+	frames := make([]*Frame, maxFrames)
+	frames[0] = mainFrame
+	return &VM{
+		constants:    bc.Constants,
+		instructions: bc.Instructions,
+		stack:        make([]interface{}, stackSize),
+		globals:      make([]interface{}, globalsSize),
+		frames:       frames,
+		framesIndex:  1,
+	}
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= maxFrames {
+		return fmt.Errorf("stack overflow")
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+func (vm *VM) push(val interface{}) error {
+	if vm.sp >= stackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	vm.stack[vm.sp] = val
+	vm.sp++
+	return nil
+}
+
+func (vm *VM) pop() interface{} {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+func (vm *VM) runtimeErr(format string, args ...interface{}) error {
+	frame := vm.currentFrame()
+	pos := frame.fn.SourceMap[frame.ip]
+	msg := fmt.Sprintf(format, args...)
+	return fmt.Errorf("%s: %s", pos, msg)
+}
+
+// Run executes the bytecode to completion, returning the first
+// runtime error encountered (if any).
+func (vm *VM) Run() error {
+	for vm.currentFrame().ip < len(vm.currentFrame().fn.Instructions)-1 {
+		frame := vm.currentFrame()
+		frame.ip++
+		ip := frame.ip
+		ins := frame.fn.Instructions
+		op := compiler.Opcode(ins[ip])
+
+		switch op {
+		case compiler.OpConstant:
+			idx := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			frame.ip += 2
+			if err := vm.push(vm.constants[idx]); err != nil {
+				return err
+			}
+		case compiler.OpTrue:
+			if err := vm.push(true); err != nil {
+				return err
+			}
+		case compiler.OpFalse:
+			if err := vm.push(false); err != nil {
+				return err
+			}
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv, compiler.OpPow:
+			right := toFloat(vm.pop())
+			left := toFloat(vm.pop())
+			var result float64
+			switch op {
+			case compiler.OpAdd:
+				result = left + right
+			case compiler.OpSub:
+				result = left - right
+			case compiler.OpMul:
+				result = left * right
+			case compiler.OpDiv:
+				if right == 0 {
+					return vm.runtimeErr("division by zero")
+				}
+				result = left / right
+			case compiler.OpPow:
+				result = math.Pow(left, right)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
+		case compiler.OpLessThan:
+			right := toFloat(vm.pop())
+			left := toFloat(vm.pop())
+			if err := vm.push(left < right); err != nil {
+				return err
+			}
+		case compiler.OpJump:
+			target := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			frame.ip = target - 1
+		case compiler.OpJumpFalse:
+			target := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			frame.ip += 2
+			if !isTrue(vm.pop()) {
+				frame.ip = target - 1
+			}
+		case compiler.OpGetGlobal:
+			idx := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			frame.ip += 2
+			if err := vm.push(vm.globals[idx]); err != nil {
+				return err
+			}
+		case compiler.OpSetGlobal:
+			idx := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			frame.ip += 2
+			vm.globals[idx] = vm.pop()
+		case compiler.OpGetLocal:
+			idx := int(ins[ip+1])
+			frame.ip++
+			if err := vm.push(vm.stack[frame.basePointer+idx]); err != nil {
+				return err
+			}
+		case compiler.OpSetLocal:
+			idx := int(ins[ip+1])
+			frame.ip++
+			vm.stack[frame.basePointer+idx] = vm.pop()
+		case compiler.OpGetBuiltin:
+			frame.ip++
+			return vm.runtimeErr("undefined function")
+		case compiler.OpCall:
+			numArgs := int(ins[ip+1])
+			frame.ip++
+			callee := vm.stack[vm.sp-1-numArgs]
+			fn, ok := callee.(*compiler.CompiledFunction)
+			if !ok {
+				return vm.runtimeErr("not a function")
+			}
+			if numArgs != fn.NumParams {
+				return vm.runtimeErr("wrong number of arguments: want %d, got %d", fn.NumParams, numArgs)
+			}
+			newFrame := &Frame{fn: fn, ip: -1, basePointer: vm.sp - numArgs}
+			if err := vm.pushFrame(newFrame); err != nil {
+				return vm.runtimeErr("%v", err)
+			}
+			vm.sp = newFrame.basePointer + fn.NumLocals
+		case compiler.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if err := vm.push(nil); err != nil {
+				return err
+			}
+		case compiler.OpReturnValue:
+			returnValue := vm.pop()
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if err := vm.push(returnValue); err != nil {
+				return err
+			}
+		case compiler.OpPrint:
+			fmt.Println(vm.pop())
+		case compiler.OpPop:
+			vm.pop()
+		default:
+			return vm.runtimeErr("unknown opcode %d", op)
+		}
+	}
+	return nil
+}
+
+func isTrue(val interface{}) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return val != nil
+}
+
+func toFloat(val interface{}) float64 {
+	switch v := val.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"nula-backend/internal/compiler"
+	"nula-backend/internal/parser"
+)
+
+// runVM parses and compiles src, runs it under the VM, and returns
+// whatever it wrote via "write" statements.
+func runVM(t *testing.T, src string) string {
+	t.Helper()
+	prog, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	bc, err := compiler.Compile(prog)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	runErr := New(bc).Run()
+	os.Stdout = old
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if runErr != nil {
+		t.Fatalf("run: %v", runErr)
+	}
+	return string(out)
+}
+
+// TestReturnFromFunction covers the feature set added after this
+// package was introduced (return statements and function literals,
+// chunk0-5): --vm must be able to run an ordinary function with a
+// return, not just straight-line code.
+func TestReturnFromFunction(t *testing.T) {
+	out := runVM(t, "fn double(x){ return x*2 }\nwrite double(3)\n")
+	if strings.TrimSpace(out) != "6" {
+		t.Errorf("got %q, want %q", out, "6")
+	}
+}
+
+// TestEarlyReturnInBranch checks that a return inside an if-branch
+// unwinds the call instead of falling through to the function's
+// trailing implicit return.
+func TestEarlyReturnInBranch(t *testing.T) {
+	src := "fn pick(flag) {\n    if flag {\n        return 1\n    }\n    return 2\n}\nwrite pick(0)\nwrite pick(1)\n"
+	out := runVM(t, src)
+	if got := strings.TrimSpace(out); got != "2\n1" {
+		t.Errorf("got %q, want %q", got, "2\n1")
+	}
+}
+
+// TestFuncLitValue covers an anonymous function assigned to a var and
+// called through it.
+func TestFuncLitValue(t *testing.T) {
+	out := runVM(t, "var triple = fn(x){ return x*3 }\nwrite triple(4)\n")
+	if strings.TrimSpace(out) != "12" {
+		t.Errorf("got %q, want %q", out, "12")
+	}
+}
+
+// TestRuntimeErrorPositionInsideCall checks that a runtime error raised
+// inside a called function reports that function's own source
+// position, not the top-level program's.
+func TestRuntimeErrorPositionInsideCall(t *testing.T) {
+	src := "fn boom(x) {\n    write 1 / 0\n}\nboom(1)\n"
+	prog, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	bc, err := compiler.Compile(prog)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	err = New(bc).Run()
+	if err == nil {
+		t.Fatal("expected a division-by-zero error, got nil")
+	}
+	const want = "2:15: division by zero"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err.Error(), want)
+	}
+}
+
+// TestFuncLitCapturingOuterLocalFailsToCompile checks that a nested fn
+// closing over an enclosing function's local (not a global) is
+// rejected at compile time rather than silently compiled into bytecode
+// that reads the wrong stack slot: OpGetLocal is always relative to
+// the *current* frame's basePointer, and there's no upvalue capture to
+// make that correct for a variable that actually lives in an outer
+// call's frame.
+func TestFuncLitCapturingOuterLocalFailsToCompile(t *testing.T) {
+	prog, err := parser.Parse("fn adder(x){ return fn(y){ return x + y } }\nvar add5 = adder(5)\nwrite add5(3)\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	_, err = compiler.Compile(prog)
+	if err == nil {
+		t.Fatal("expected a compile error for capturing an enclosing function's local, got nil")
+	}
+	if !strings.Contains(err.Error(), "closes over") {
+		t.Errorf("error %q does not mention the capture it rejected", err.Error())
+	}
+}
+
+// TestUnboundedRecursionReturnsStackOverflowError checks that blowing
+// past maxFrames call frames is a clean runtime error, like push()
+// already gives for the value stack, rather than an unrecovered Go
+// panic from indexing vm.frames out of bounds.
+func TestUnboundedRecursionReturnsStackOverflowError(t *testing.T) {
+	prog, err := parser.Parse("fn rec(){ return rec() }\nrec()\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	bc, err := compiler.Compile(prog)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	err = New(bc).Run()
+	if err == nil {
+		t.Fatal("expected a stack overflow error, got nil")
+	}
+	if !strings.Contains(err.Error(), "stack overflow") {
+		t.Errorf("error %q does not mention stack overflow", err.Error())
+	}
+}
@@ -1,20 +1,52 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"nula-backend/internal/compiler"
 	"nula-backend/internal/interpreter"
 	"nula-backend/internal/parser"
+	"nula-backend/internal/printer"
+	"nula-backend/internal/resolver"
+	"nula-backend/internal/vm"
 )
 
 func main() {
-	if len(os.Args) < 3 || os.Args[1] != "run" {
-		fmt.Println("Usage: nula-backend run <file.nula>")
+	useVM := flag.Bool("vm", false, "execute via the bytecode compiler/VM instead of the tree-walking interpreter")
+	allowEmbedded := flag.String("allow-embedded", "", "comma-separated list of embedded languages to permit (e.g. \"python,sh\"); none are allowed by default")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "run":
+		if len(args) < 2 {
+			usage()
+			os.Exit(1)
+		}
+		runFile(args[1], *useVM, *allowEmbedded)
+	case "fmt":
+		runFmt(args[1:])
+	default:
+		usage()
 		os.Exit(1)
 	}
-	filePath := os.Args[2]
+}
+
+func usage() {
+	fmt.Println("Usage: nula-backend [--vm] [--allow-embedded=langs] run <file.nula>")
+	fmt.Println("       nula-backend fmt [-w] <file.nula>")
+}
 
+func runFile(filePath string, useVM bool, allowEmbedded string) {
 	code, err := os.ReadFile(filePath)
 	if err != nil {
 		fmt.Printf("Error reading file: %v\n", err)
@@ -22,17 +54,90 @@ func main() {
 	}
 
 	// Parse the code
-	ast, err := parser.Parse(string(code))
+	prog, err := parser.ParseFile(filePath, string(code))
 	if err != nil {
-		fmt.Printf("Parse error: %v\n", err)
+		fmt.Printf("Parse error:\n%v\n", err)
 		os.Exit(1)
 	}
 
-	// Interpret
-	globalScope := interpreter.NewScope(nil)
-	err = interpreter.Interpret(ast, globalScope)
+	if resolveErrs, warnings := resolver.Resolve(prog); resolveErrs != nil {
+		fmt.Printf("Resolve error:\n%v\n", resolveErrs)
+		os.Exit(1)
+	} else {
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+	}
+
+	if useVM {
+		bc, err := compiler.Compile(prog)
+		if err != nil {
+			fmt.Printf("Compile error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := vm.New(bc).Run(); err != nil {
+			fmt.Printf("Runtime error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Interpret. Imports are resolved relative to the script's own
+	// directory and file imports are allowed for the "run" command,
+	// since the user is explicitly choosing to execute this file.
+	modules := interpreter.NewFileModuleGetter(filepath.Dir(filePath))
+	modules.AllowFileImport = true
+	var langs []string
+	if allowEmbedded != "" {
+		langs = strings.Split(allowEmbedded, ",")
+	}
+	globalScope := interpreter.NewScope(nil, interpreter.SetModuleGetter(modules), interpreter.AllowEmbedded(langs...))
+	err = interpreter.Interpret(prog, globalScope)
 	if err != nil {
 		fmt.Printf("Runtime error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// runFmt implements "nula-backend fmt [-w] <file.nula>": parses with
+// comments retained and prints the canonical form, mirroring gofmt's
+// default of writing the formatted source to stdout unless -w is given
+// to rewrite the file in place.
+func runFmt(args []string) {
+	write := false
+	var files []string
+	for _, a := range args {
+		if a == "-w" {
+			write = true
+			continue
+		}
+		files = append(files, a)
+	}
+	if len(files) != 1 {
+		usage()
+		os.Exit(1)
+	}
+	filePath := files[0]
+
+	code, err := os.ReadFile(filePath)
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+
+	prog, err := parser.ParseFile(filePath, string(code), parser.ParseComments)
+	if err != nil {
+		fmt.Printf("Parse error:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	formatted := printer.Print(prog)
+	if write {
+		if err := os.WriteFile(filePath, []byte(formatted), 0o644); err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Print(formatted)
+}